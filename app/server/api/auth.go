@@ -2,9 +2,11 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/metrics"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/middleware"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
@@ -13,15 +15,19 @@ import (
 
 // AuthAPI 认证API
 type AuthAPI struct {
-	userService *service.UserService
-	cfg         *config.Config
+	userService    *service.UserService
+	tenantService  *service.TenantService
+	sessionService *service.SessionService
+	cfg            *config.Config
 }
 
 // NewAuthAPI 创建认证API
 func NewAuthAPI(cfg *config.Config) *AuthAPI {
 	return &AuthAPI{
-		userService: &service.UserService{},
-		cfg:         cfg,
+		userService:    &service.UserService{},
+		tenantService:  &service.TenantService{},
+		sessionService: &service.SessionService{},
+		cfg:            cfg,
 	}
 }
 
@@ -37,6 +43,7 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Tenant   string `json:"tenant"` // 可选，登录后默认进入的租户编码
 }
 
 // Register 用户注册
@@ -52,7 +59,7 @@ func (a *AuthAPI) Register(c *gin.Context) {
 	}
 
 	// 检查用户是否已存在
-	exists, err := a.userService.UserExists(req.Username, req.Email)
+	exists, err := a.userService.UserExists(c.Request.Context(), req.Username, req.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -79,7 +86,7 @@ func (a *AuthAPI) Register(c *gin.Context) {
 		Status:   1,
 	}
 
-	if err := a.userService.CreateUser(user); err != nil {
+	if err := a.userService.CreateUser(c.Request.Context(), user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "创建用户失败",
@@ -115,8 +122,9 @@ func (a *AuthAPI) Login(c *gin.Context) {
 	}
 
 	// 查找用户
-	user, err := a.userService.GetUserByUsername(req.Username)
+	user, err := a.userService.GetUserByUsername(c.Request.Context(), req.Username)
 	if err != nil {
+		metrics.LoginAttempt(metrics.LoginInvalidCredentials)
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code":    401,
 			"message": "用户名或密码错误",
@@ -126,6 +134,7 @@ func (a *AuthAPI) Login(c *gin.Context) {
 
 	// 验证密码
 	if err := a.userService.VerifyPassword(user, req.Password); err != nil {
+		metrics.LoginAttempt(metrics.LoginInvalidCredentials)
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code":    401,
 			"message": "用户名或密码错误",
@@ -135,6 +144,7 @@ func (a *AuthAPI) Login(c *gin.Context) {
 
 	// 检查用户状态
 	if user.Status != 1 {
+		metrics.LoginAttempt(metrics.LoginDisabled)
 		c.JSON(http.StatusForbidden, gin.H{
 			"code":    403,
 			"message": "用户已被禁用",
@@ -142,15 +152,29 @@ func (a *AuthAPI) Login(c *gin.Context) {
 		return
 	}
 
-	// 获取用户角色
-	roles, _ := rbac.GetRolesForUser(user.Username)
+	// 获取用户角色（含经由用户组继承的角色）
+	roles, _ := rbac.GetImplicitRolesForUser(user.Username, rbac.GlobalDomain)
 	if len(roles) == 0 {
-		roles = []string{"user"} // 默认角色
+		roles = []string{rbac.DefaultRole} // 默认角色
+	}
+
+	// 校验登录租户
+	if req.Tenant != "" {
+		if _, err := a.tenantService.ResolveTenant(req.Tenant, user.ID, roles); err != nil {
+			metrics.LoginAttempt(metrics.LoginTenantDenied)
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "无法进入该租户",
+				"error":   err.Error(),
+			})
+			return
+		}
 	}
 
 	// 生成Token
-	token, err := middleware.GenerateToken(user.ID, user.Username, roles, &a.cfg.JWT)
+	token, err := middleware.GenerateToken(user.ID, user.Username, roles, req.Tenant, &a.cfg.JWT)
 	if err != nil {
+		metrics.LoginAttempt(metrics.LoginError)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "生成Token失败",
@@ -159,17 +183,59 @@ func (a *AuthAPI) Login(c *gin.Context) {
 		return
 	}
 
+	data := gin.H{
+		"user_id":  user.ID,
+		"username": user.Username,
+		"nickname": user.Nickname,
+		"email":    user.Email,
+		"roles":    roles,
+		"tenant":   req.Tenant,
+	}
+	if middleware.TokenInResponse() {
+		data["token"] = token
+	}
+	// Cookie 模式下 token 只写入 HttpOnly Cookie，前端从响应或 CSRF Cookie 取得 CSRF token
+	if middleware.CookieAuthEnabled() {
+		data["csrf_token"] = middleware.SetAuthCookies(c, token, time.Now().Add(a.cfg.JWT.ExpireTime))
+	}
+
+	metrics.LoginAttempt(metrics.LoginSuccess)
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "登录成功",
-		"data": gin.H{
-			"token":    token,
-			"user_id":  user.ID,
-			"username": user.Username,
-			"nickname": user.Nickname,
-			"email":    user.Email,
-			"roles":    roles,
-		},
+		"data":    data,
+	})
+}
+
+// Logout 退出登录：在服务端吊销当前 Token，并删除认证 Cookie
+// 没有携带有效 Token 时只删除 Cookie
+func (a *AuthAPI) Logout(c *gin.Context) {
+	if middleware.CookieAuthEnabled() {
+		middleware.ClearAuthCookies(c)
+	}
+
+	if claims := middleware.RequestClaims(c); claims != nil && claims.ExpiresAt != nil {
+		ttl := time.Until(claims.ExpiresAt.Time)
+		var err error
+		if claims.ID != "" {
+			err = a.sessionService.RevokeToken(c.Request.Context(), claims.ID, ttl)
+		} else {
+			// 之前签发的 Token 没有 ID，只能吊销该用户的全部会话
+			err = a.sessionService.RevokeUserSessions(c.Request.Context(), claims.UserID, ttl)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "退出登录失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已退出登录",
 	})
 }
 
@@ -184,7 +250,7 @@ func (a *AuthAPI) GetProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := a.userService.GetUserByID(userID.(uint))
+	user, err := a.userService.GetUserByID(c.Request.Context(), userID.(uint))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -194,8 +260,8 @@ func (a *AuthAPI) GetProfile(c *gin.Context) {
 		return
 	}
 
-	// 获取用户角色
-	roles, _ := rbac.GetRolesForUser(user.Username)
+	// 获取用户角色（含经由用户组继承的角色）
+	roles, _ := rbac.GetImplicitRolesForUser(user.Username, rbac.GlobalDomain)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
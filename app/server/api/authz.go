@@ -0,0 +1,222 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/middleware"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"gorm.io/gorm"
+)
+
+// maxAuthzChecks 单次批量检查的最大条数
+const maxAuthzChecks = 100
+
+// AuthzAPI 权限检查API
+type AuthzAPI struct {
+	userService   *service.UserService
+	tenantService *service.TenantService
+}
+
+// NewAuthzAPI 创建权限检查API
+func NewAuthzAPI() *AuthzAPI {
+	return &AuthzAPI{
+		userService:   &service.UserService{},
+		tenantService: &service.TenantService{},
+	}
+}
+
+// AuthzCheckItem 单条权限检查
+type AuthzCheckItem struct {
+	Resource string `json:"resource" binding:"required"`
+	Action   string `json:"action" binding:"required"`
+}
+
+// AuthzCheckRequest 批量权限检查请求
+type AuthzCheckRequest struct {
+	Checks []AuthzCheckItem `json:"checks" binding:"required,min=1,dive"`
+}
+
+// Check 批量检查当前用户对一组 (resource, action) 的访问权限，供前端决定按钮是否显示
+func (a *AuthzAPI) Check(c *gin.Context) {
+	var req AuthzCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if len(req.Checks) > maxAuthzChecks {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "单次检查条数过多",
+			"limit":   maxAuthzChecks,
+		})
+		return
+	}
+
+	roles := c.GetStringSlice("roles")
+	domain := c.GetString("tenant")
+
+	results := make([]gin.H, 0, len(req.Checks))
+	for _, check := range req.Checks {
+		allowed, err := rbac.Authorize(roles, domain, check.Resource, check.Action)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "权限检查失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		results = append(results, gin.H{
+			"resource": check.Resource,
+			"action":   check.Action,
+			"allowed":  allowed,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data": gin.H{
+			"tenant":  domain,
+			"results": results,
+		},
+	})
+}
+
+// Explain 解释指定用户访问资源的判定结果（命中的策略及角色继承链）
+// 查询参数：user、obj、act 必填，tenant 可选，默认使用当前租户
+func (a *AuthzAPI) Explain(c *gin.Context) {
+	username := c.Query("user")
+	resource := c.Query("obj")
+	action := c.Query("act")
+	if username == "" || resource == "" || action == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "缺少参数 user、obj 或 act",
+		})
+		return
+	}
+
+	domain := c.DefaultQuery("tenant", c.GetString("tenant"))
+	if domain == "" {
+		domain = rbac.GlobalDomain
+	}
+
+	user, err := a.userService.GetUserByUsername(c.Request.Context(), username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "用户不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "权限解释失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// 与 Tenant、CasbinAuth 中间件走同一条路径：先校验能否进入租户，再加载资源属性供条件策略求值
+	req := &rbac.Request{
+		Domain: domain,
+		Object: resource,
+		Action: action,
+		User: rbac.Subject{
+			ID:       user.ID,
+			Username: user.Username,
+		},
+	}
+	scoped := c.Copy()
+	if domain != rbac.GlobalDomain {
+		globalRoles, err := rbac.ResolveRoles(user.Username, rbac.GlobalDomain)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "权限解释失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		tenant, err := a.tenantService.ResolveTenant(domain, user.ID, globalRoles)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "租户不存在",
+				"tenant":  domain,
+			})
+			return
+		case errors.Is(err, service.ErrTenantDisabled), errors.Is(err, service.ErrNotTenantMember):
+			c.JSON(http.StatusOK, gin.H{
+				"code":    200,
+				"message": "成功",
+				"data": &rbac.Explanation{
+					Subject:     user.Username,
+					Domain:      domain,
+					Object:      resource,
+					Action:      action,
+					ActiveRoles: []string{},
+					Roles:       []rbac.RoleSource{},
+					Reason:      err.Error(),
+				},
+			})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "权限解释失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		req.User.TenantID = tenant.ID
+		scoped.Request = c.Request.WithContext(database.WithTenant(c.Request.Context(), tenant.ID))
+	}
+
+	req.Resource, err = middleware.LoadResource(scoped, action, resource)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "加载资源失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	explanation, err := rbac.Explain(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "权限解释失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data":    explanation,
+	})
+}
+
+// PolicyStatus 查看本节点的策略版本与同步状态
+func (a *AuthzAPI) PolicyStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data":    rbac.GetPolicyStatus(),
+	})
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/logging"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/metrics"
+)
+
+// maxCSPReportSize 单次上报的最大字节数
+const maxCSPReportSize = 64 << 10
+
+// cspDirectives 指标中按名称统计的指令，其余归为 other，避免伪造上报撑爆标签
+var cspDirectives = map[string]bool{
+	"default-src": true, "script-src": true, "script-src-elem": true, "script-src-attr": true,
+	"style-src": true, "style-src-elem": true, "style-src-attr": true, "img-src": true,
+	"font-src": true, "connect-src": true, "media-src": true, "object-src": true,
+	"frame-src": true, "child-src": true, "worker-src": true, "manifest-src": true,
+	"frame-ancestors": true, "form-action": true, "base-uri": true,
+}
+
+// CSPReportAPI CSP 违规上报收集
+type CSPReportAPI struct{}
+
+// NewCSPReportAPI 创建 CSP 上报API
+func NewCSPReportAPI() *CSPReportAPI {
+	return &CSPReportAPI{}
+}
+
+// CSPViolation 一条 CSP 违规
+type CSPViolation struct {
+	DocumentURI        string `json:"document-uri"`
+	BlockedURI         string `json:"blocked-uri"`
+	EffectiveDirective string `json:"effective-directive"`
+	ViolatedDirective  string `json:"violated-directive"`
+	Disposition        string `json:"disposition"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+	ColumnNumber       int    `json:"column-number"`
+	Sample             string `json:"script-sample"`
+}
+
+// reportingAPIViolation Reporting API（application/reports+json）中的违规内容
+type reportingAPIViolation struct {
+	DocumentURL        string `json:"documentURL"`
+	BlockedURL         string `json:"blockedURL"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	Disposition        string `json:"disposition"`
+	SourceFile         string `json:"sourceFile"`
+	LineNumber         int    `json:"lineNumber"`
+	ColumnNumber       int    `json:"columnNumber"`
+	Sample             string `json:"sample"`
+}
+
+// Report 接收浏览器的 CSP 违规上报，兼容 report-uri 的 {"csp-report": {...}} 格式
+// 和 Reporting API 的数组格式；违规写入警告日志并计入指标，始终返回 204
+func (a *CSPReportAPI) Report(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxCSPReportSize))
+	if err != nil {
+		c.Status(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	violations, err := parseCSPReport(body)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	logger := logging.FromContext(c.Request.Context())
+	for _, v := range violations {
+		directive := v.EffectiveDirective
+		if directive == "" {
+			directive, _, _ = strings.Cut(v.ViolatedDirective, " ")
+		}
+		logger.Warn("CSP 违规",
+			"directive", directive,
+			"blocked_uri", v.BlockedURI,
+			"document_uri", v.DocumentURI,
+			"source_file", v.SourceFile,
+			"line", v.LineNumber,
+			"column", v.ColumnNumber,
+			"disposition", v.Disposition,
+			"sample", v.Sample,
+			"user_agent", c.Request.UserAgent(),
+		)
+		if !cspDirectives[directive] {
+			directive = "other"
+		}
+		metrics.CSPViolation(directive)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// parseCSPReport 解析两种上报格式
+func parseCSPReport(body []byte) ([]CSPViolation, error) {
+	var legacy struct {
+		Report *CSPViolation `json:"csp-report"`
+	}
+	if err := json.Unmarshal(body, &legacy); err == nil && legacy.Report != nil {
+		return []CSPViolation{*legacy.Report}, nil
+	}
+
+	var reports []struct {
+		Type string                `json:"type"`
+		Body reportingAPIViolation `json:"body"`
+	}
+	if err := json.Unmarshal(body, &reports); err != nil {
+		return nil, err
+	}
+	violations := make([]CSPViolation, 0, len(reports))
+	for _, r := range reports {
+		if r.Type != "csp-violation" {
+			continue
+		}
+		violations = append(violations, CSPViolation{
+			DocumentURI:        r.Body.DocumentURL,
+			BlockedURI:         r.Body.BlockedURL,
+			EffectiveDirective: r.Body.EffectiveDirective,
+			Disposition:        r.Body.Disposition,
+			SourceFile:         r.Body.SourceFile,
+			LineNumber:         r.Body.LineNumber,
+			ColumnNumber:       r.Body.ColumnNumber,
+			Sample:             r.Body.Sample,
+		})
+	}
+	return violations, nil
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/logging"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/version"
+)
+
+// startTime 进程启动时间，用于计算运行时长
+var startTime = time.Now()
+
+// DebugAPI 诊断接口：pprof、运行时统计、生效配置和日志级别
+type DebugAPI struct {
+	cfg *config.Config
+}
+
+// NewDebugAPI 创建诊断API
+func NewDebugAPI(cfg *config.Config) *DebugAPI {
+	return &DebugAPI{cfg: cfg}
+}
+
+// Pprof 提供 net/http/pprof 的全部内容：name 为空时返回索引页，其余为具名 profile
+// 或 cmdline、profile、symbol、trace
+func (a *DebugAPI) Pprof(c *gin.Context) {
+	switch name := c.Param("name"); name {
+	case "":
+		pprof.Index(c.Writer, c.Request)
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, fitWriteTimeout(c, 30))
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, fitWriteTimeout(c, 1))
+	default:
+		if rpprof.Lookup(name) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "profile 不存在",
+				"name":    name,
+			})
+			return
+		}
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// fitWriteTimeout 让按 seconds 持续采集的 profile、trace 不受服务器写超时限制，defaultSeconds 为 pprof 的默认采集时长
+// 挂在主端口时 WriteTimeout（默认 15s）短于 profile 默认的 30s：先清除本次请求的写超时；
+// 响应不支持设置写超时（如被没有 Unwrap 的中间件包装）时，把 seconds 限制在 WriteTimeout 以内，避免采集完成后响应已无法写出
+func fitWriteTimeout(c *gin.Context, defaultSeconds int) *http.Request {
+	srv, ok := c.Request.Context().Value(http.ServerContextKey).(*http.Server)
+	if !ok || srv.WriteTimeout <= 0 {
+		return c.Request
+	}
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err == nil {
+		return c.Request
+	}
+
+	limit := max(int(srv.WriteTimeout/time.Second)-1, 1)
+	seconds, err := strconv.Atoi(c.Query("seconds"))
+	if err != nil || seconds <= 0 {
+		seconds = defaultSeconds
+	}
+	if seconds <= limit {
+		return c.Request
+	}
+
+	req := c.Request.Clone(c.Request.Context())
+	query := req.URL.Query()
+	query.Set("seconds", strconv.Itoa(limit))
+	req.URL.RawQuery = query.Encode()
+	req.Form = nil
+	return req
+}
+
+// Runtime 返回协程数、内存和 GC 统计等运行时信息
+func (a *DebugAPI) Runtime(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var lastPause time.Duration
+	if mem.NumGC > 0 {
+		lastPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256])
+	}
+	var lastGC time.Time
+	if mem.LastGC > 0 {
+		lastGC = time.Unix(0, int64(mem.LastGC))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"version":    version.AppVersion,
+			"commit":     version.GitCommit,
+			"go_version": runtime.Version(),
+			"uptime":     time.Since(startTime).Round(time.Second).String(),
+			"goroutines": runtime.NumGoroutine(),
+			"num_cpu":    runtime.NumCPU(),
+			"gomaxprocs": runtime.GOMAXPROCS(0),
+			"cgo_calls":  runtime.NumCgoCall(),
+			"memory": gin.H{
+				"alloc":         mem.Alloc,
+				"total_alloc":   mem.TotalAlloc,
+				"sys":           mem.Sys,
+				"heap_alloc":    mem.HeapAlloc,
+				"heap_inuse":    mem.HeapInuse,
+				"heap_idle":     mem.HeapIdle,
+				"heap_released": mem.HeapReleased,
+				"heap_objects":  mem.HeapObjects,
+				"stack_inuse":   mem.StackInuse,
+				"mallocs":       mem.Mallocs,
+				"frees":         mem.Frees,
+			},
+			"gc": gin.H{
+				"num_gc":          mem.NumGC,
+				"num_forced_gc":   mem.NumForcedGC,
+				"next_gc":         mem.NextGC,
+				"last_gc":         lastGC,
+				"last_pause":      lastPause.String(),
+				"pause_total":     time.Duration(mem.PauseTotalNs).String(),
+				"gc_cpu_fraction": mem.GCCPUFraction,
+			},
+		},
+	})
+}
+
+// Config 返回当前生效的配置，密码和密钥等敏感项已隐去
+func (a *DebugAPI) Config(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    a.cfg.Redacted(),
+	})
+}
+
+// LogLevelRequest 修改日志级别请求
+type LogLevelRequest struct {
+	Level string `json:"level" binding:"required"`
+}
+
+// GetLogLevel 返回当前的日志级别
+func (a *DebugAPI) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data": gin.H{
+			"level": logging.Level().String(),
+		},
+	})
+}
+
+// SetLogLevel 运行时修改全局日志级别，无需重启；重启或 SIGHUP 重新加载配置后恢复为 LOG_LEVEL
+func (a *DebugAPI) SetLogLevel(c *gin.Context) {
+	var req LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	previous := logging.Level()
+	if err := logging.SetLevel(req.Level); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "日志级别无效",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// 以 Warn 记录，使切换到较高级别时这条日志仍会输出；单独调试地址上没有认证，username 为空
+	username, _ := c.Get("username")
+	logging.FromContext(c.Request.Context()).Warn("日志级别已修改",
+		"username", username, "from", previous.String(), "to", logging.Level().String())
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "日志级别已修改",
+		"data": gin.H{
+			"previous": previous.String(),
+			"level":    logging.Level().String(),
+		},
+	})
+}
@@ -0,0 +1,362 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"gorm.io/gorm"
+)
+
+// GroupAPI 用户组API
+type GroupAPI struct {
+	groupService *service.GroupService
+}
+
+// NewGroupAPI 创建用户组API
+func NewGroupAPI() *GroupAPI {
+	return &GroupAPI{
+		groupService: &service.GroupService{},
+	}
+}
+
+// groupError 输出用户组操作的错误响应
+func groupError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "用户组、用户或角色不存在",
+		})
+	case errors.Is(err, service.ErrGroupCycle), errors.Is(err, service.ErrGroupTooDeep), errors.Is(err, service.ErrGroupTenantMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": message,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrRoleNotGrantable):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": message,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrRoleNotHeld):
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": message,
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// parseGroupID 解析路径中的用户组ID
+func parseGroupID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户组ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetGroups 获取用户组列表
+func (a *GroupAPI) GetGroups(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	groups, total, err := a.groupService.GetAllGroups(c.Request.Context(), page, pageSize)
+	if err != nil {
+		groupError(c, "获取用户组列表失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data": gin.H{
+			"list":      groups,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetGroupByID 根据ID获取用户组
+func (a *GroupAPI) GetGroupByID(c *gin.Context) {
+	id, ok := parseGroupID(c)
+	if !ok {
+		return
+	}
+
+	group, err := a.groupService.GetGroupByID(c.Request.Context(), id)
+	if err != nil {
+		groupError(c, "获取用户组失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data":    group,
+	})
+}
+
+// CreateGroup 创建用户组
+func (a *GroupAPI) CreateGroup(c *gin.Context) {
+	var group model.Group
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := a.groupService.CreateGroup(c.Request.Context(), &group); err != nil {
+		groupError(c, "创建用户组失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "创建成功",
+		"data":    group,
+	})
+}
+
+// UpdateGroup 更新用户组
+func (a *GroupAPI) UpdateGroup(c *gin.Context) {
+	id, ok := parseGroupID(c)
+	if !ok {
+		return
+	}
+
+	var group model.Group
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	group.ID = id
+	if err := a.groupService.UpdateGroup(c.Request.Context(), &group); err != nil {
+		groupError(c, "更新用户组失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "更新成功",
+		"data":    group,
+	})
+}
+
+// DeleteGroup 删除用户组
+func (a *GroupAPI) DeleteGroup(c *gin.Context) {
+	id, ok := parseGroupID(c)
+	if !ok {
+		return
+	}
+
+	if err := a.groupService.DeleteGroup(c.Request.Context(), id); err != nil {
+		groupError(c, "删除用户组失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除成功",
+	})
+}
+
+// SetParent 设置父组（parent_id 为 null 时变为顶层组）
+func (a *GroupAPI) SetParent(c *gin.Context) {
+	id, ok := parseGroupID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ParentID *uint `json:"parent_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := a.groupService.SetParent(c.Request.Context(), id, req.ParentID); err != nil {
+		groupError(c, "设置父组失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "设置成功",
+	})
+}
+
+// GetMembers 获取用户组成员
+func (a *GroupAPI) GetMembers(c *gin.Context) {
+	id, ok := parseGroupID(c)
+	if !ok {
+		return
+	}
+
+	members, err := a.groupService.GetMembers(c.Request.Context(), id)
+	if err != nil {
+		groupError(c, "获取成员失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data":    members,
+	})
+}
+
+// AddMembers 添加用户组成员
+func (a *GroupAPI) AddMembers(c *gin.Context) {
+	id, ok := parseGroupID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserIDs []uint `json:"user_ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := a.groupService.AddMembers(c.Request.Context(), id, req.UserIDs); err != nil {
+		groupError(c, "添加成员失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "添加成功",
+	})
+}
+
+// RemoveMember 移除用户组成员
+func (a *GroupAPI) RemoveMember(c *gin.Context) {
+	id, ok := parseGroupID(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	if err := a.groupService.RemoveMember(c.Request.Context(), id, uint(userID)); err != nil {
+		groupError(c, "移除成员失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "移除成功",
+	})
+}
+
+// GetRoles 获取用户组的角色
+func (a *GroupAPI) GetRoles(c *gin.Context) {
+	id, ok := parseGroupID(c)
+	if !ok {
+		return
+	}
+
+	roles, err := a.groupService.GetRoles(c.Request.Context(), id)
+	if err != nil {
+		groupError(c, "获取角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data":    roles,
+	})
+}
+
+// AddRole 为用户组授予角色
+func (a *GroupAPI) AddRole(c *gin.Context) {
+	id, ok := parseGroupID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := a.groupService.AddRole(c.Request.Context(), c.GetString("username"), id, req.Role); err != nil {
+		groupError(c, "授予角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "授予成功",
+	})
+}
+
+// RemoveRole 撤销用户组的角色
+func (a *GroupAPI) RemoveRole(c *gin.Context) {
+	id, ok := parseGroupID(c)
+	if !ok {
+		return
+	}
+
+	if err := a.groupService.RemoveRole(c.Request.Context(), id, c.Param("role")); err != nil {
+		groupError(c, "撤销角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "撤销成功",
+	})
+}
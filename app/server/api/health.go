@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/health"
+)
+
+// HealthAPI 存活和就绪探针
+type HealthAPI struct {
+	checker *health.Checker
+}
+
+// NewHealthAPI 创建探针API
+func NewHealthAPI() *HealthAPI {
+	return &HealthAPI{
+		checker: health.Default,
+	}
+}
+
+// Livez 存活探针：不检查依赖，进程能处理请求即返回 200
+func (a *HealthAPI) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, a.checker.Live())
+}
+
+// Readyz 就绪探针：依赖检查全部通过时返回 200，否则返回 503 及各项检查结果
+// 服务开始优雅关闭后始终返回 503，使负载均衡停止转发新请求
+func (a *HealthAPI) Readyz(c *gin.Context) {
+	report := a.checker.Ready(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -26,7 +26,7 @@ func (a *PermissionAPI) GetPermissions(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 
-	permissions, total, err := a.permissionService.GetAllPermissions(page, pageSize)
+	permissions, total, err := a.permissionService.GetAllPermissions(c.Request.Context(), page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -59,7 +59,7 @@ func (a *PermissionAPI) GetPermissionByID(c *gin.Context) {
 		return
 	}
 
-	permission, err := a.permissionService.GetPermissionByID(uint(id))
+	permission, err := a.permissionService.GetPermissionByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    404,
@@ -87,7 +87,7 @@ func (a *PermissionAPI) CreatePermission(c *gin.Context) {
 		return
 	}
 
-	if err := a.permissionService.CreatePermission(&permission); err != nil {
+	if err := a.permissionService.CreatePermission(c.Request.Context(), &permission); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "创建权限失败",
@@ -125,7 +125,7 @@ func (a *PermissionAPI) UpdatePermission(c *gin.Context) {
 	}
 
 	permission.ID = uint(id)
-	if err := a.permissionService.UpdatePermission(&permission); err != nil {
+	if err := a.permissionService.UpdatePermission(c.Request.Context(), &permission); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "更新权限失败",
@@ -152,7 +152,7 @@ func (a *PermissionAPI) DeletePermission(c *gin.Context) {
 		return
 	}
 
-	if err := a.permissionService.DeletePermission(uint(id)); err != nil {
+	if err := a.permissionService.DeletePermission(c.Request.Context(), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "删除权限失败",
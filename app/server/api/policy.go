@@ -0,0 +1,225 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"gorm.io/gorm"
+)
+
+// PolicyAPI 策略管理API
+type PolicyAPI struct {
+	policyService *service.PolicyService
+}
+
+// NewPolicyAPI 创建策略管理API
+func NewPolicyAPI() *PolicyAPI {
+	return &PolicyAPI{
+		policyService: &service.PolicyService{},
+	}
+}
+
+// policyError 输出策略操作的错误响应
+func policyError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPolicy), errors.Is(err, service.ErrInvalidEffect):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": message,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrPolicyNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": message,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, rbac.ErrConcurrentPolicyChange):
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": message,
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// roleDomain 返回角色成员操作的域，默认为全局域
+func roleDomain(c *gin.Context) string {
+	return c.DefaultQuery("dom", rbac.GlobalDomain)
+}
+
+// GetPolicies 获取策略列表，可按 sub、dom、obj、act、eft 过滤
+func (a *PolicyAPI) GetPolicies(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	filter := rbac.Policy{
+		Subject: c.Query("sub"),
+		Domain:  c.Query("dom"),
+		Object:  c.Query("obj"),
+		Action:  c.Query("act"),
+		Effect:  c.Query("eft"),
+	}
+
+	policies, total, err := a.policyService.GetPolicies(filter, page, pageSize)
+	if err != nil {
+		policyError(c, "获取策略列表失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data": gin.H{
+			"list":      policies,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// AddPolicies 批量添加策略
+func (a *PolicyAPI) AddPolicies(c *gin.Context) {
+	var req struct {
+		Policies []rbac.Policy `json:"policies" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	added, err := a.policyService.AddPolicies(req.Policies)
+	if err != nil {
+		policyError(c, "添加策略失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "添加成功",
+		"data": gin.H{
+			"added": added,
+		},
+	})
+}
+
+// UpdatePolicy 修改策略
+func (a *PolicyAPI) UpdatePolicy(c *gin.Context) {
+	var req struct {
+		Old rbac.Policy `json:"old" binding:"required"`
+		New rbac.Policy `json:"new" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := a.policyService.UpdatePolicy(req.Old, req.New); err != nil {
+		policyError(c, "修改策略失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "修改成功",
+	})
+}
+
+// RemovePolicies 批量删除策略
+func (a *PolicyAPI) RemovePolicies(c *gin.Context) {
+	var req struct {
+		Policies []rbac.Policy `json:"policies" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	removed, err := a.policyService.RemovePolicies(req.Policies)
+	if err != nil {
+		policyError(c, "删除策略失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除成功",
+		"data": gin.H{
+			"removed": removed,
+		},
+	})
+}
+
+// GetRoleMembers 获取角色的直接成员
+func (a *PolicyAPI) GetRoleMembers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data":    a.policyService.GetRoleMembers(c.Param("role"), roleDomain(c)),
+	})
+}
+
+// AddRoleMember 为用户授予角色
+func (a *PolicyAPI) AddRoleMember(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Domain   string `json:"dom"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if req.Domain == "" {
+		req.Domain = rbac.GlobalDomain
+	}
+
+	if err := a.policyService.AddRoleMember(c.Request.Context(), c.Param("role"), req.Username, req.Domain); err != nil {
+		policyError(c, "授予角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "授予成功",
+	})
+}
+
+// RemoveRoleMember 撤销用户的角色
+func (a *PolicyAPI) RemoveRoleMember(c *gin.Context) {
+	if err := a.policyService.RemoveRoleMember(c.Param("role"), c.Param("username"), roleDomain(c)); err != nil {
+		policyError(c, "撤销角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "撤销成功",
+	})
+}
@@ -1,12 +1,15 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"gorm.io/gorm"
 )
 
 // RoleAPI 角色API
@@ -26,7 +29,7 @@ func (a *RoleAPI) GetRoles(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 
-	roles, total, err := a.roleService.GetAllRoles(page, pageSize)
+	roles, total, err := a.roleService.GetAllRoles(c.Request.Context(), page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -59,7 +62,7 @@ func (a *RoleAPI) GetRoleByID(c *gin.Context) {
 		return
 	}
 
-	role, err := a.roleService.GetRoleByID(uint(id))
+	role, err := a.roleService.GetRoleByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    404,
@@ -87,12 +90,8 @@ func (a *RoleAPI) CreateRole(c *gin.Context) {
 		return
 	}
 
-	if err := a.roleService.CreateRole(&role); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
-			"message": "创建角色失败",
-			"error":   err.Error(),
-		})
+	if err := a.roleService.CreateRole(c.Request.Context(), &role); err != nil {
+		roleError(c, "创建角色失败", err)
 		return
 	}
 
@@ -125,12 +124,8 @@ func (a *RoleAPI) UpdateRole(c *gin.Context) {
 	}
 
 	role.ID = uint(id)
-	if err := a.roleService.UpdateRole(&role); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
-			"message": "更新角色失败",
-			"error":   err.Error(),
-		})
+	if err := a.roleService.UpdateRole(c.Request.Context(), &role); err != nil {
+		roleError(c, "更新角色失败", err)
 		return
 	}
 
@@ -152,12 +147,8 @@ func (a *RoleAPI) DeleteRole(c *gin.Context) {
 		return
 	}
 
-	if err := a.roleService.DeleteRole(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    500,
-			"message": "删除角色失败",
-			"error":   err.Error(),
-		})
+	if err := a.roleService.DeleteRole(c.Request.Context(), uint(id)); err != nil {
+		roleError(c, "删除角色失败", err)
 		return
 	}
 
@@ -191,7 +182,7 @@ func (a *RoleAPI) AssignPermission(c *gin.Context) {
 		return
 	}
 
-	if err := a.roleService.AssignPermissionToRole(uint(roleID), req.PermissionID); err != nil {
+	if err := a.roleService.AssignPermissionToRole(c.Request.Context(), uint(roleID), req.PermissionID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "分配权限失败",
@@ -206,3 +197,133 @@ func (a *RoleAPI) AssignPermission(c *gin.Context) {
 	})
 }
 
+
+// roleError 输出角色操作的错误响应
+func roleError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, rbac.ErrRoleCycle), errors.Is(err, rbac.ErrRoleTooDeep), errors.Is(err, service.ErrInvalidRoleScope),
+		errors.Is(err, service.ErrRoleRename):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": message,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": message,
+			"error":   "角色不存在",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// parseRoleID 解析路径中的角色ID，失败时输出 400 响应
+func parseRoleID(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的角色ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetParents 获取角色直接继承的父角色
+func (a *RoleAPI) GetParents(c *gin.Context) {
+	id, ok := parseRoleID(c, "id")
+	if !ok {
+		return
+	}
+
+	parents, err := a.roleService.GetParents(c.Request.Context(), id)
+	if err != nil {
+		roleError(c, "获取父角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data":    parents,
+	})
+}
+
+// AddParent 让角色继承父角色
+func (a *RoleAPI) AddParent(c *gin.Context) {
+	id, ok := parseRoleID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ParentID uint `json:"parent_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := a.roleService.AddParent(c.Request.Context(), id, req.ParentID); err != nil {
+		roleError(c, "添加父角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "添加成功",
+	})
+}
+
+// RemoveParent 取消角色对父角色的继承
+func (a *RoleAPI) RemoveParent(c *gin.Context) {
+	id, ok := parseRoleID(c, "id")
+	if !ok {
+		return
+	}
+	parentID, ok := parseRoleID(c, "parent_id")
+	if !ok {
+		return
+	}
+
+	if err := a.roleService.RemoveParent(c.Request.Context(), id, parentID); err != nil {
+		roleError(c, "移除父角色失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "移除成功",
+	})
+}
+
+// GetResolvedPermissions 获取角色经继承展开后的完整权限集合
+func (a *RoleAPI) GetResolvedPermissions(c *gin.Context) {
+	id, ok := parseRoleID(c, "id")
+	if !ok {
+		return
+	}
+
+	resolved, err := a.roleService.ResolveRole(c.Request.Context(), id)
+	if err != nil {
+		roleError(c, "获取角色权限失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data":    resolved,
+	})
+}
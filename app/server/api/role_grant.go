@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"gorm.io/gorm"
+)
+
+// RoleGrantAPI 限时角色授权（临时提权）API
+type RoleGrantAPI struct {
+	grantService *service.RoleGrantService
+}
+
+// NewRoleGrantAPI 创建限时角色授权API
+func NewRoleGrantAPI() *RoleGrantAPI {
+	return &RoleGrantAPI{
+		grantService: &service.RoleGrantService{},
+	}
+}
+
+// grantError 输出限时授权操作的错误响应
+func grantError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidGrantWindow):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": message,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrSelfApproval):
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": message,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": message,
+			"error":   "授权申请或用户不存在",
+		})
+	case errors.Is(err, service.ErrGrantConflict), errors.Is(err, service.ErrGrantStatus):
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    409,
+			"message": message,
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": message,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// RequestGrant 为当前用户申请临时角色
+// 有效期由 expires_at 指定，或由 duration（如 1h、30m）从 not_before（缺省为当前时间）起算
+func (a *RoleGrantAPI) RequestGrant(c *gin.Context) {
+	var req struct {
+		Role      string     `json:"role" binding:"required"`
+		Domain    string     `json:"dom"`
+		NotBefore *time.Time `json:"not_before"`
+		ExpiresAt *time.Time `json:"expires_at"`
+		Duration  string     `json:"duration"`
+		Reason    string     `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if req.ExpiresAt == nil {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "请指定 expires_at 或有效的 duration",
+			})
+			return
+		}
+		start := time.Now()
+		if req.NotBefore != nil {
+			start = *req.NotBefore
+		}
+		expiresAt := start.Add(duration)
+		req.ExpiresAt = &expiresAt
+	}
+
+	username := c.GetString("username")
+	grant := &model.RoleGrant{
+		Username:  username,
+		Role:      req.Role,
+		Domain:    req.Domain,
+		NotBefore: req.NotBefore,
+		ExpiresAt: *req.ExpiresAt,
+		Reason:    req.Reason,
+	}
+	if err := a.grantService.RequestGrant(c.Request.Context(), username, grant); err != nil {
+		grantError(c, "提交申请失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "申请已提交，等待审批",
+		"data":    grant,
+	})
+}
+
+// GetMyGrants 获取当前用户的临时角色申请
+func (a *RoleGrantAPI) GetMyGrants(c *gin.Context) {
+	a.listGrants(c, c.GetString("username"))
+}
+
+// GetGrants 获取临时角色申请列表，可按 status、username 过滤
+func (a *RoleGrantAPI) GetGrants(c *gin.Context) {
+	a.listGrants(c, c.Query("username"))
+}
+
+func (a *RoleGrantAPI) listGrants(c *gin.Context, username string) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	grants, total, err := a.grantService.ListGrants(c.Request.Context(), c.Query("status"), username, page, pageSize)
+	if err != nil {
+		grantError(c, "获取申请列表失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data": gin.H{
+			"list":      grants,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// ApproveGrant 批准临时角色申请
+func (a *RoleGrantAPI) ApproveGrant(c *gin.Context) {
+	a.review(c, "批准", a.grantService.ApproveGrant)
+}
+
+// RejectGrant 拒绝临时角色申请
+func (a *RoleGrantAPI) RejectGrant(c *gin.Context) {
+	a.review(c, "拒绝", a.grantService.RejectGrant)
+}
+
+// RevokeGrant 提前撤销已批准的临时角色
+func (a *RoleGrantAPI) RevokeGrant(c *gin.Context) {
+	a.review(c, "撤销", a.grantService.RevokeGrant)
+}
+
+// review 执行审批类操作，操作者为当前用户
+func (a *RoleGrantAPI) review(c *gin.Context, verb string, fn func(ctx context.Context, id uint, actor string) (*model.RoleGrant, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的申请ID",
+		})
+		return
+	}
+
+	grant, err := fn(c.Request.Context(), uint(id), c.GetString("username"))
+	if err != nil {
+		grantError(c, verb+"失败", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已" + verb,
+		"data":    grant,
+	})
+}
@@ -0,0 +1,366 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"gorm.io/gorm"
+)
+
+// TenantAPI 租户API
+type TenantAPI struct {
+	tenantService *service.TenantService
+}
+
+// NewTenantAPI 创建租户API
+func NewTenantAPI() *TenantAPI {
+	return &TenantAPI{
+		tenantService: &service.TenantService{},
+	}
+}
+
+// GetTenants 获取租户列表
+func (a *TenantAPI) GetTenants(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	tenants, total, err := a.tenantService.GetAllTenants(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取租户列表失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data": gin.H{
+			"list":      tenants,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetTenantByID 根据ID获取租户
+func (a *TenantAPI) GetTenantByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的租户ID",
+		})
+		return
+	}
+
+	tenant, err := a.tenantService.GetTenantByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "租户不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data":    tenant,
+	})
+}
+
+// CreateTenant 创建租户
+func (a *TenantAPI) CreateTenant(c *gin.Context) {
+	var tenant model.Tenant
+	if err := c.ShouldBindJSON(&tenant); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := a.tenantService.CreateTenant(&tenant); err != nil {
+		if errors.Is(err, service.ErrInvalidTenantCode) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "创建租户失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "创建租户失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "创建成功",
+		"data":    tenant,
+	})
+}
+
+// UpdateTenant 更新租户
+func (a *TenantAPI) UpdateTenant(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的租户ID",
+		})
+		return
+	}
+
+	var tenant model.Tenant
+	if err := c.ShouldBindJSON(&tenant); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	tenant.ID = uint(id)
+	if err := a.tenantService.UpdateTenant(&tenant); err != nil {
+		if errors.Is(err, service.ErrInvalidTenantCode) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "更新租户失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "更新租户失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "更新成功",
+		"data":    tenant,
+	})
+}
+
+// DeleteTenant 删除租户
+func (a *TenantAPI) DeleteTenant(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的租户ID",
+		})
+		return
+	}
+
+	if err := a.tenantService.DeleteTenant(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "删除租户失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "删除成功",
+	})
+}
+
+// GetMyTenants 获取当前用户所属的租户
+func (a *TenantAPI) GetMyTenants(c *gin.Context) {
+	members, err := a.tenantService.GetUserTenants(c.GetUint("user_id"), c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取租户列表失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data": gin.H{
+			"list":    members,
+			"current": c.GetString("tenant"),
+		},
+	})
+}
+
+// memberContext 返回成员操作的租户上下文
+// /tenants/:id/members 使用路径中的租户，/tenant/members 使用当前请求的租户
+func memberContext(c *gin.Context) (context.Context, bool) {
+	if param := c.Param("id"); param != "" {
+		id, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "无效的租户ID",
+			})
+			return nil, false
+		}
+		return database.WithTenant(c.Request.Context(), uint(id)), true
+	}
+
+	ctx := c.Request.Context()
+	if _, ok := database.TenantFromContext(ctx); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "未指定租户，请设置 X-Tenant 请求头",
+		})
+		return nil, false
+	}
+	return ctx, true
+}
+
+// GetMembers 获取租户成员列表
+func (a *TenantAPI) GetMembers(c *gin.Context) {
+	ctx, ok := memberContext(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	members, total, err := a.tenantService.GetMembers(ctx, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取成员列表失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data": gin.H{
+			"list":      members,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// AddMember 添加租户成员并授予租户内角色
+func (a *TenantAPI) AddMember(c *gin.Context) {
+	ctx, ok := memberContext(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID uint     `json:"user_id" binding:"required"`
+		Roles  []string `json:"roles"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	member, err := a.tenantService.AddMember(ctx, c.GetString("username"), req.UserID, req.Roles)
+	if err != nil {
+		if errors.Is(err, service.ErrRoleNotGrantable) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "添加成员失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrRoleNotHeld) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "添加成员失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "租户或用户不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "添加成员失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "添加成功",
+		"data":    member,
+	})
+}
+
+// RemoveMember 移除租户成员
+func (a *TenantAPI) RemoveMember(c *gin.Context) {
+	ctx, ok := memberContext(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	if err := a.tenantService.RemoveMember(ctx, uint(userID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "成员不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "移除成员失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "移除成功",
+	})
+}
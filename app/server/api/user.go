@@ -1,23 +1,32 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"gorm.io/gorm"
 )
 
 // UserAPI 用户API
 type UserAPI struct {
 	userService *service.UserService
+	cfg         *config.Config
 }
 
 // NewUserAPI 创建用户API
-func NewUserAPI() *UserAPI {
+func NewUserAPI(cfg *config.Config) *UserAPI {
 	return &UserAPI{
 		userService: &service.UserService{},
+		cfg:         cfg,
 	}
 }
 
@@ -26,7 +35,7 @@ func (a *UserAPI) GetUsers(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 
-	users, total, err := a.userService.GetAllUsers(page, pageSize)
+	users, total, err := a.userService.GetAllUsers(c.Request.Context(), page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -59,7 +68,7 @@ func (a *UserAPI) GetUserByID(c *gin.Context) {
 		return
 	}
 
-	user, err := a.userService.GetUserByID(uint(id))
+	user, err := a.userService.GetUserByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    404,
@@ -75,6 +84,24 @@ func (a *UserAPI) GetUserByID(c *gin.Context) {
 	})
 }
 
+// LoadUserResource 加载 /api/users/:id 的资源属性，用户本人即为资源所有者
+func (a *UserAPI) LoadUserResource(c *gin.Context) (*rbac.Resource, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	user, err := a.userService.GetUserByID(c.Request.Context(), uint(id))
+	if err != nil {
+		return nil, err
+	}
+	return &rbac.Resource{
+		ID:      user.ID,
+		OwnerID: user.ID,
+		Status:  user.Status,
+	}, nil
+}
+
 // CreateUser 创建用户
 func (a *UserAPI) CreateUser(c *gin.Context) {
 	var user model.User
@@ -87,7 +114,7 @@ func (a *UserAPI) CreateUser(c *gin.Context) {
 		return
 	}
 
-	if err := a.userService.CreateUser(&user); err != nil {
+	if err := a.userService.CreateUser(c.Request.Context(), &user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "创建用户失败",
@@ -125,7 +152,7 @@ func (a *UserAPI) UpdateUser(c *gin.Context) {
 	}
 
 	user.ID = uint(id)
-	if err := a.userService.UpdateUser(&user); err != nil {
+	if err := a.userService.UpdateUser(c.Request.Context(), &user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "更新用户失败",
@@ -152,7 +179,7 @@ func (a *UserAPI) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := a.userService.DeleteUser(uint(id)); err != nil {
+	if err := a.userService.DeleteUser(c.Request.Context(), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "删除用户失败",
@@ -179,7 +206,9 @@ func (a *UserAPI) AssignRole(c *gin.Context) {
 	}
 
 	var req struct {
-		RoleID uint `json:"role_id" binding:"required"`
+		RoleID    uint       `json:"role_id" binding:"required"`
+		NotBefore *time.Time `json:"not_before"`
+		ExpiresAt *time.Time `json:"expires_at"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -191,7 +220,23 @@ func (a *UserAPI) AssignRole(c *gin.Context) {
 		return
 	}
 
-	if err := a.userService.AssignRoleToUser(uint(userID), req.RoleID); err != nil {
+	if err := a.userService.AssignRoleToUser(c.Request.Context(), uint(userID), req.RoleID, req.NotBefore, req.ExpiresAt); err != nil {
+		if errors.Is(err, service.ErrInvalidGrantWindow) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "分配角色失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrGrantConflict) {
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": "分配角色失败",
+				"error":   err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
 			"message": "分配角色失败",
@@ -206,3 +251,322 @@ func (a *UserAPI) AssignRole(c *gin.Context) {
 	})
 }
 
+// DisableUser 禁用用户（同时吊销其所有会话）
+func (a *UserAPI) DisableUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	if currentID, _ := c.Get("user_id"); currentID == uint(id) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "不能禁用当前登录用户",
+		})
+		return
+	}
+
+	if err := a.userService.DisableUser(c.Request.Context(), uint(id), a.cfg.JWT.ExpireTime); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "用户不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "禁用用户失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "禁用成功",
+	})
+}
+
+// EnableUser 启用用户
+func (a *UserAPI) EnableUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	if err := a.userService.EnableUser(c.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "用户不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "启用用户失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "启用成功",
+	})
+}
+
+// GetDeletedUsers 获取已删除用户列表
+func (a *UserAPI) GetDeletedUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	users, total, err := a.userService.GetDeletedUsers(c.Request.Context(), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取已删除用户列表失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data": gin.H{
+			"list":      users,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// RestoreUser 恢复已删除用户
+func (a *UserAPI) RestoreUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	user, err := a.userService.RestoreUser(c.Request.Context(), uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "已删除用户不存在",
+			})
+		case errors.Is(err, service.ErrUserConflict), errors.Is(err, service.ErrUserPurged):
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": "恢复用户失败",
+				"error":   err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "恢复用户失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "恢复成功",
+		"data":    user,
+	})
+}
+
+// PurgeUser 彻底清除用户（匿名化个人信息并移除角色规则，不可恢复）
+func (a *UserAPI) PurgeUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	if err := a.userService.PurgeUser(c.Request.Context(), uint(id), a.cfg.JWT.ExpireTime); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "用户不存在",
+			})
+		case errors.Is(err, service.ErrUserPurged):
+			c.JSON(http.StatusConflict, gin.H{
+				"code":    409,
+				"message": "用户已被清除",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "清除用户失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "清除成功",
+	})
+}
+
+// maxImportSize 导入文件大小上限
+const maxImportSize = 10 << 20 // 10MB
+
+// ImportUsers 批量导入用户
+// 支持 multipart 上传（字段名 file）或直接以 text/csv、application/json 作为请求体；
+// 查询参数 dry_run=true 仅校验不写入，partial=true 跳过错误行并提交其余行
+func (a *UserAPI) ImportUsers(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	var reader io.Reader = c.Request.Body
+	format := service.DetectFormat(c.ContentType())
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "缺少上传文件",
+				"error":   err.Error(),
+			})
+			return
+		}
+		defer file.Close()
+		reader = file
+		format = service.DetectFormat(header.Filename)
+	}
+	if f := c.Query("format"); f != "" {
+		format = f
+	}
+
+	users, err := service.ParseImportUsers(reader, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "解析导入文件失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	opts := service.ImportOptions{
+		DryRun:  c.Query("dry_run") == "true",
+		Partial: c.Query("partial") == "true",
+	}
+	result, err := a.userService.ImportUsers(c.Request.Context(), users, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "导入用户失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	status, message := http.StatusOK, "导入成功"
+	switch {
+	case result.DryRun:
+		message = "校验完成"
+	case !result.Committed:
+		status, message = http.StatusUnprocessableEntity, "导入失败，已全部回滚"
+	case result.Failed > 0:
+		message = "部分导入成功"
+	case len(result.Warnings) > 0:
+		message = "导入成功，但部分角色未能分配"
+	}
+
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": message,
+		"data":    result,
+	})
+}
+
+// ExportUsers 导出用户（format=csv|json，默认 csv）
+func (a *UserAPI) ExportUsers(c *gin.Context) {
+	format := c.DefaultQuery("format", service.FormatCSV)
+	if format != service.FormatCSV && format != service.FormatJSON {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "不支持的导出格式",
+		})
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == service.FormatJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	filename := fmt.Sprintf("users-%s.%s", time.Now().Format("20060102150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// 响应头已发送，出错时只能中断输出并记录
+	if err := a.userService.ExportUsers(c.Request.Context(), c.Writer, format); err != nil {
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// GetEffectiveRoles 获取用户在当前租户中的有效角色及其来源
+func (a *UserAPI) GetEffectiveRoles(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	domain := c.GetString("tenant")
+	roles, err := a.userService.GetEffectiveRoles(c.Request.Context(), uint(id), domain)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "用户不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取有效角色失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "成功",
+		"data": gin.H{
+			"tenant": domain,
+			"roles":  roles,
+		},
+	})
+}
@@ -4,17 +4,22 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/health"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/lifecycle"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/logging"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/metrics"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/middleware"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/router"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/tracing"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/transport"
 	"github.com/urfave/cli/v3"
 )
 
@@ -42,6 +47,11 @@ var Command = &cli.Command{
 					Aliases: []string{"p"},
 					Value:   false,
 				},
+				&cli.StringFlag{
+					Name:    "env-file",
+					Usage:   "环境变量文件，启动时及收到 SIGHUP 时读取",
+					Sources: cli.EnvVars("SERVER_ENV_FILE"),
+				},
 			},
 		},
 		{
@@ -49,6 +59,9 @@ var Command = &cli.Command{
 			Usage:  "运行数据库迁移",
 			Action: action.migrate,
 		},
+		usersCommand,
+		policyCommand,
+		permissionsCommand,
 	},
 }
 
@@ -56,92 +69,320 @@ type Action struct{}
 
 func (a *Action) start(ctx context.Context, cmd *cli.Command) error {
 	// 加载配置
+	envFile := cmd.String("env-file")
+	if envFile != "" {
+		if err := config.LoadEnvFile(envFile); err != nil {
+			return fmt.Errorf("读取环境变量文件失败: %w", err)
+		}
+	}
 	cfg := config.Load()
-	slog.Info("配置加载完成")
-
-	// 初始化数据库
-	if err := database.InitPostgreSQL(&cfg.Database); err != nil {
-		slog.Error("PostgreSQL 初始化失败", "error", err)
+	if err := logging.Init(&cfg.Log); err != nil {
 		return err
 	}
-	defer database.ClosePostgreSQL()
+	slog.Info("配置加载完成", "log_level", cfg.Log.Level, "log_format", cfg.Log.Format, "env_file", envFile)
 
-	// 初始化Redis
-	if err := database.InitRedis(&cfg.Redis); err != nil {
-		slog.Error("Redis 初始化失败", "error", err)
-		return err
-	}
-	defer database.CloseRedis()
+	// 各组件按依赖顺序启动，按相反顺序停止；SIGHUP 时依次重新加载
+	app := lifecycle.New(ctx, cfg.Server.HookTimeout)
 
-	// 自动迁移数据库（如果指定）
-	if cmd.Bool("migrate") {
-		if err := database.AutoMigrate(); err != nil {
-			slog.Error("数据库迁移失败", "error", err)
+	// 重新读取环境变量文件，应用无需重启即可生效的配置：日志级别和就绪检查超时
+	app.Append(lifecycle.Hook{
+		Name: "config",
+		OnReload: func(ctx context.Context) error {
+			if envFile != "" {
+				if err := config.LoadEnvFile(envFile); err != nil {
+					return err
+				}
+			}
+			next := config.Load()
+			if err := logging.SetLevel(next.Log.Level); err != nil {
+				return err
+			}
+			health.Default.SetTimeout(next.Server.HealthTimeout)
+			slog.Info("配置已重新加载", "log_level", next.Log.Level, "health_timeout", next.Server.HealthTimeout)
+			return nil
+		},
+	})
+
+	// 链路追踪需在数据库和 Redis 之前初始化，使其插件使用已配置的 TracerProvider
+	var shutdownTracing func(context.Context) error
+	app.Append(lifecycle.Hook{
+		Name: "tracing",
+		OnStart: func(ctx context.Context) error {
+			var err error
+			shutdownTracing, err = tracing.Init(ctx, &cfg.Tracing)
 			return err
-		}
+		},
+		OnStop: func(ctx context.Context) error {
+			return shutdownTracing(ctx)
+		},
+	})
+
+	app.Append(lifecycle.Hook{
+		Name:      "postgres",
+		DependsOn: []string{"tracing"},
+		OnStart: func(ctx context.Context) error {
+			return database.InitPostgreSQL(&cfg.Database)
+		},
+		OnStop: func(ctx context.Context) error {
+			return database.ClosePostgreSQL()
+		},
+	})
+
+	// 自动迁移数据库（如果指定），耗时取决于数据量，不限时
+	if cmd.Bool("migrate") {
+		app.Append(lifecycle.Hook{
+			Name:      "migrate",
+			DependsOn: []string{"postgres"},
+			Timeout:   -1,
+			OnStart: func(ctx context.Context) error {
+				return database.AutoMigrate()
+			},
+		})
 	}
 
-	// 初始化Casbin
+	app.Append(lifecycle.Hook{
+		Name:      "redis",
+		DependsOn: []string{"tracing"},
+		OnStart: func(ctx context.Context) error {
+			return database.InitRedis(&cfg.Redis)
+		},
+		OnStop: func(ctx context.Context) error {
+			return database.CloseRedis()
+		},
+	})
+
+	app.Append(lifecycle.Hook{
+		Name:      "casbin",
+		DependsOn: []string{"postgres", "redis"},
+		OnStart: func(ctx context.Context) error {
+			return a.startCasbin(ctx, cfg, cmd.Bool("init-policy"))
+		},
+		OnStop: func(ctx context.Context) error {
+			rbac.StopWatcher()
+			return nil
+		},
+		OnReload: func(ctx context.Context) error {
+			return rbac.ReloadPolicy()
+		},
+	})
+
+	// 加载临时角色的有效期并定时清理到期授权
+	app.Append(lifecycle.Hook{
+		Name:      "grant_sweeper",
+		DependsOn: []string{"casbin"},
+		OnStart: func(ctx context.Context) error {
+			return service.StartGrantSweeper(app.Context(), cfg.Casbin.SweepInterval)
+		},
+		OnStop: func(ctx context.Context) error {
+			service.StopGrantSweeper()
+			return nil
+		},
+	})
+
+	// 单独的管理端口提供指标
+	if cfg.Metrics.Enabled {
+		var metricsSrv *http.Server
+		app.Append(lifecycle.Hook{
+			Name:      "metrics",
+			DependsOn: []string{"postgres", "redis"},
+			OnStart: func(ctx context.Context) error {
+				if err := metrics.RegisterDatabase(); err != nil {
+					return err
+				}
+				if cfg.Metrics.Addr == "" {
+					return nil
+				}
+				if !isLoopbackAddr(cfg.Metrics.Addr) {
+					slog.Warn("指标端口未做认证，建议只监听本机回环地址", "addr", cfg.Metrics.Addr)
+				}
+				ln, err := net.Listen("tcp", cfg.Metrics.Addr)
+				if err != nil {
+					return err
+				}
+				mux := http.NewServeMux()
+				mux.Handle(cfg.Metrics.Path, metrics.Handler())
+				metricsSrv = &http.Server{
+					Handler:           mux,
+					ReadHeaderTimeout: cfg.Server.ReadTimeout,
+				}
+				go func() {
+					if err := metricsSrv.Serve(ln); err != nil && err != http.ErrServerClosed {
+						app.Fail(fmt.Errorf("指标服务: %w", err))
+					}
+				}()
+				slog.Info("指标服务启动成功", "addr", cfg.Metrics.Addr, "path", cfg.Metrics.Path)
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				if metricsSrv == nil {
+					return nil
+				}
+				return metricsSrv.Shutdown(ctx)
+			},
+		})
+	}
+
+	// 单独的调试地址提供诊断接口，未设置写超时以便采集较长时间的 profile
+	if cfg.Debug.Enabled && cfg.Debug.Addr != "" {
+		var debugSrv *http.Server
+		app.Append(lifecycle.Hook{
+			Name: "debug",
+			OnStart: func(ctx context.Context) error {
+				if !isLoopbackAddr(cfg.Debug.Addr) {
+					slog.Warn("调试接口未做认证，建议只监听本机回环地址", "addr", cfg.Debug.Addr)
+				}
+				ln, err := net.Listen("tcp", cfg.Debug.Addr)
+				if err != nil {
+					return err
+				}
+				debugSrv = &http.Server{
+					Handler:           router.SetupDebugRouter(cfg),
+					ReadHeaderTimeout: cfg.Server.ReadTimeout,
+				}
+				go func() {
+					if err := debugSrv.Serve(ln); err != nil && err != http.ErrServerClosed {
+						app.Fail(fmt.Errorf("调试服务: %w", err))
+					}
+				}()
+				slog.Info("调试服务启动成功", "addr", cfg.Debug.Addr)
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				if debugSrv == nil {
+					return nil
+				}
+				return debugSrv.Shutdown(ctx)
+			},
+		})
+	}
+
+	// HTTP 服务（TCP、TLS、HTTP/3、Unix 套接字和 HTTPS 重定向按配置启用），最先停止
+	var srv *transport.Server
+	app.Append(lifecycle.Hook{
+		Name:      "http",
+		DependsOn: []string{"casbin", "grant_sweeper"},
+		Timeout:   cfg.Server.ShutdownDelay + cfg.Server.ShutdownTimeout,
+		OnStart: func(ctx context.Context) error {
+			var err error
+			srv, err = a.newHTTPServer(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			if err := srv.Start(app.Context()); err != nil {
+				return err
+			}
+			go func() {
+				select {
+				case err := <-srv.Errors():
+					app.Fail(err)
+				case <-app.Context().Done():
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			// 先让就绪检查失败，等待负载均衡摘除本实例后再停止接收请求
+			health.Default.SetShuttingDown()
+			if cfg.Server.ShutdownDelay > 0 {
+				slog.Info("等待负载均衡摘除实例", "delay", cfg.Server.ShutdownDelay)
+				select {
+				case <-time.After(cfg.Server.ShutdownDelay):
+				case <-ctx.Done():
+				}
+			}
+			return srv.Shutdown(ctx)
+		},
+		OnReload: func(ctx context.Context) error {
+			return srv.Reload()
+		},
+	})
+
+	return app.Run(cfg.Server.ShutdownDelay + cfg.Server.ShutdownTimeout)
+}
+
+// startCasbin 初始化 Casbin 并按配置同步策略和角色继承关系
+func (a *Action) startCasbin(ctx context.Context, cfg *config.Config, initPolicy bool) error {
 	if err := rbac.InitCasbin(&cfg.Casbin); err != nil {
-		slog.Error("Casbin 初始化失败", "error", err)
 		return err
 	}
 
 	// 初始化默认策略（如果指定）
-	if cmd.Bool("init-policy") {
+	if initPolicy {
 		if err := rbac.InitDefaultPolicies(); err != nil {
-			slog.Error("初始化默认策略失败", "error", err)
-			return err
+			return fmt.Errorf("初始化默认策略失败: %w", err)
 		}
 	}
 
+	// 声明式模式：以策略文件为准
+	if cfg.Casbin.Declarative {
+		diff, err := rbac.SyncPolicyFile(cfg.Casbin.PolicyFile, cfg.Casbin.PruneRoles)
+		if err != nil {
+			return fmt.Errorf("按策略文件 %s 同步失败: %w", cfg.Casbin.PolicyFile, err)
+		}
+		slog.Info("已按策略文件同步策略", "file", cfg.Casbin.PolicyFile,
+			"added", len(diff.AddPolicies)+len(diff.AddGroupings),
+			"removed", len(diff.RemovePolicies)+len(diff.RemoveGroupings))
+	}
+
+	// 将角色继承关系补写到 Casbin
+	roleService := &service.RoleService{}
+	added, err := roleService.SyncHierarchy(ctx)
+	if err != nil {
+		return fmt.Errorf("同步角色继承关系失败: %w", err)
+	}
+	if added > 0 {
+		slog.Info("已同步角色继承关系", "added", added)
+	}
+	return nil
+}
+
+// newHTTPServer 初始化认证、设置路由并同步权限目录，创建尚未开始监听的 HTTP 服务
+func (a *Action) newHTTPServer(ctx context.Context, cfg *config.Config) (*transport.Server, error) {
 	// 初始化JWT
-	middleware.InitJWT(&cfg.JWT)
+	if err := middleware.InitJWT(&cfg.JWT); err != nil {
+		return nil, fmt.Errorf("认证配置无效: %w", err)
+	}
 
 	// 设置路由
+	health.Default.SetTimeout(cfg.Server.HealthTimeout)
 	r := router.SetupRouter(cfg)
 
-	// 创建HTTP服务器
-	addr := fmt.Sprintf(":%d", cfg.Server.Port)
-	srv := &http.Server{
-		Addr:           addr,
-		Handler:        r,
-		ReadTimeout:    cfg.Server.ReadTimeout,
-		WriteTimeout:   cfg.Server.WriteTimeout,
-		MaxHeaderBytes: 1 << 20, // 1MB
-	}
-
-	// 启动服务器
-	go func() {
-		slog.Info("服务器启动成功", "addr", addr, "mode", cfg.Server.Mode)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.Error("服务器启动失败", "error", err)
-			os.Exit(1)
+	// 同步权限目录（只增改，不删除孤立权限）
+	if cfg.Casbin.SyncCatalog {
+		permissionService := &service.PermissionService{}
+		report, err := permissionService.SyncCatalog(ctx, router.PermissionCatalog(r), true, false)
+		if err != nil {
+			return nil, fmt.Errorf("同步权限目录失败: %w", err)
 		}
-	}()
-
-	// 优雅关闭
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	slog.Info("正在关闭服务器...")
+		slog.Info("权限目录已同步", "created", len(report.Created), "updated", len(report.Updated),
+			"unprotected_routes", len(report.Unprotected))
+		for _, permission := range report.Orphaned {
+			slog.Warn("权限没有对应的路由", "name", permission.Name, "resource", permission.Resource, "action", permission.Action)
+		}
+	}
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	return transport.New(cfg, r)
+}
 
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		slog.Error("服务器关闭异常", "error", err)
-		return err
+// isLoopbackAddr 监听地址是否只在本机回环地址上，主机名只认 localhost
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
 	}
-
-	slog.Info("服务器已关闭")
-	return nil
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
 
 func (a *Action) migrate(ctx context.Context, cmd *cli.Command) error {
 	// 加载配置
 	cfg := config.Load()
+	if err := logging.Init(&cfg.Log); err != nil {
+		return err
+	}
 
 	// 初始化数据库
 	if err := database.InitPostgreSQL(&cfg.Database); err != nil {
@@ -158,4 +399,35 @@ func (a *Action) migrate(ctx context.Context, cmd *cli.Command) error {
 
 	slog.Info("数据库迁移完成")
 	return nil
-}
\ No newline at end of file
+}
+
+// initBackend 初始化数据库与 Casbin，供无需启动 HTTP 服务的子命令使用
+// Redis 可选：连接成功时子命令对策略的修改会广播给正在运行的服务节点
+func (a *Action) initBackend(cfg *config.Config) error {
+	if err := logging.Init(&cfg.Log); err != nil {
+		return err
+	}
+	if err := database.InitPostgreSQL(&cfg.Database); err != nil {
+		slog.Error("PostgreSQL 初始化失败", "error", err)
+		return err
+	}
+
+	if err := database.InitRedis(&cfg.Redis); err != nil {
+		slog.Warn("Redis 不可用，策略变更将不会通知其他节点", "error", err)
+		_ = database.CloseRedis()
+		database.RDB = nil
+	}
+
+	if err := rbac.InitCasbin(&cfg.Casbin); err != nil {
+		slog.Error("Casbin 初始化失败", "error", err)
+		return err
+	}
+	return nil
+}
+
+// closeBackend 释放 initBackend 打开的资源
+func (a *Action) closeBackend() {
+	rbac.StopWatcher()
+	_ = database.CloseRedis()
+	_ = database.ClosePostgreSQL()
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/router"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"github.com/urfave/cli/v3"
+)
+
+// permissionsCommand 权限目录管理命令
+var permissionsCommand = &cli.Command{
+	Name:  "permissions",
+	Usage: "权限目录管理",
+	Commands: []*cli.Command{
+		{
+			Name:   "sync",
+			Usage:  "将路由登记的权限目录同步到 permissions 表，并列出孤立权限和未做权限检查的路由",
+			Action: action.syncPermissions,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "只输出计划中的变更，不写入数据库",
+				},
+				&cli.BoolFlag{
+					Name:  "prune",
+					Usage: "删除没有对应路由的孤立权限",
+				},
+			},
+		},
+	},
+}
+
+func (a *Action) syncPermissions(ctx context.Context, cmd *cli.Command) error {
+	cfg := config.Load()
+	if err := a.initPolicyBackend(cfg); err != nil {
+		return err
+	}
+	defer a.closeBackend()
+
+	// 只为收集路由而构建路由表，关闭 gin 的调试输出
+	cfg.Server.Mode = gin.ReleaseMode
+	catalog := router.PermissionCatalog(router.SetupRouter(cfg))
+
+	permissionService := &service.PermissionService{}
+	report, err := permissionService.SyncCatalog(ctx, catalog, !cmd.Bool("dry-run"), cmd.Bool("prune"))
+	if err != nil {
+		return err
+	}
+	return report.Print(os.Stdout)
+}
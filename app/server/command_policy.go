@@ -0,0 +1,324 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"github.com/urfave/cli/v3"
+	"gorm.io/gorm/logger"
+)
+
+// policyRuleFlags add/remove 共用的参数
+var policyRuleFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "dom",
+		Usage: "租户域，默认为全局域",
+		Value: rbac.GlobalDomain,
+	},
+	&cli.BoolFlag{
+		Name:  "role",
+		Usage: "操作角色分配（g 规则），参数为 <user> <role>",
+	},
+	&cli.StringFlag{
+		Name:  "eft",
+		Usage: "策略效果 allow|deny",
+		Value: rbac.EftAllow,
+	},
+	&cli.IntFlag{
+		Name:  "priority",
+		Usage: "优先级，数值越小优先级越高（仅优先级模式下生效）",
+		Value: rbac.DefaultPriority,
+	},
+}
+
+// policyCommand 策略管理命令
+var policyCommand = &cli.Command{
+	Name:  "policy",
+	Usage: "权限策略管理",
+	Commands: []*cli.Command{
+		{
+			Name:   "list",
+			Usage:  "列出策略，可按 sub、dom、obj、act、eft 过滤",
+			Action: action.listPolicies,
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "sub", Usage: "主体（角色）"},
+				&cli.StringFlag{Name: "dom", Usage: "租户域"},
+				&cli.StringFlag{Name: "obj", Usage: "资源"},
+				&cli.StringFlag{Name: "act", Usage: "操作"},
+				&cli.StringFlag{Name: "eft", Usage: "效果 allow|deny"},
+			},
+		},
+		{
+			Name:      "add",
+			Usage:     "添加策略或角色分配",
+			ArgsUsage: "<sub> <obj> <act> | --role <user> <role>",
+			Action:    action.addPolicy,
+			Flags:     policyRuleFlags,
+		},
+		{
+			Name:      "remove",
+			Usage:     "删除策略或角色分配",
+			ArgsUsage: "<sub> <obj> <act> | --role <user> <role>",
+			Action:    action.removePolicy,
+			Flags:     policyRuleFlags,
+		},
+		{
+			Name:      "import",
+			Usage:     "从策略文件（CSV 或 YAML）导入规则，已存在的规则会被跳过",
+			ArgsUsage: "<file>",
+			Action:    action.importPolicies,
+		},
+		{
+			Name:   "sync",
+			Usage:  "对比策略文件（CSV 或 YAML）与数据库中的策略，输出计划中的变更",
+			Action: action.syncPolicies,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "file",
+					Usage: "策略文件，默认使用 CASBIN_POLICY_FILE",
+				},
+				&cli.BoolFlag{
+					Name:  "apply",
+					Usage: "应用变更",
+				},
+				&cli.BoolFlag{
+					Name:  "prune-roles",
+					Usage: "同时删除文件中不存在的角色分配（g 规则）",
+				},
+			},
+		},
+		{
+			Name:   "export",
+			Usage:  "导出全部规则为 CSV 策略文件",
+			Action: action.exportPolicies,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "output",
+					Usage:   "输出文件，默认输出到标准输出",
+					Aliases: []string{"o"},
+				},
+				&cli.BoolFlag{
+					Name:  "defaults",
+					Usage: "只导出内置的默认策略，无需连接数据库（用于生成 configs/rbac_policy.csv）",
+				},
+			},
+		},
+	},
+}
+
+// defaultPolicyHeader policy export --defaults 输出的文件头
+const defaultPolicyHeader = `# 内置默认策略，由 server policy export --defaults 生成
+# 可在此基础上追加自定义 p、p2 规则；按文件同步时内置规则不会被删除，角色分配（g 规则）请通过接口或 policy add --role 维护`
+
+// initPolicyBackend 初始化策略命令所需的后端，并静默 GORM 日志以免混入命令输出
+func (a *Action) initPolicyBackend(cfg *config.Config) error {
+	if err := a.initBackend(cfg); err != nil {
+		return err
+	}
+	database.DB.Logger = database.DB.Logger.LogMode(logger.Silent)
+	return nil
+}
+
+// policyRule 解析 add/remove 的参数，返回规则是否为 g 规则
+func policyRule(cmd *cli.Command) ([]string, bool, error) {
+	args := cmd.Args().Slice()
+	dom := cmd.String("dom")
+
+	if cmd.Bool("role") {
+		if len(args) != 2 {
+			return nil, true, errors.New("请指定 <user> <role>")
+		}
+		return []string{args[0], args[1], dom}, true, nil
+	}
+	if len(args) != 3 {
+		return nil, false, errors.New("请指定 <sub> <obj> <act>")
+	}
+	if !rbac.ValidEft(cmd.String("eft")) {
+		return nil, false, errors.New("eft 只能为 allow 或 deny")
+	}
+	policy := rbac.Policy{
+		Subject:  args[0],
+		Domain:   dom,
+		Object:   args[1],
+		Action:   args[2],
+		Effect:   cmd.String("eft"),
+		Priority: int(cmd.Int("priority")),
+	}
+	return policy.Rule(), false, nil
+}
+
+func (a *Action) listPolicies(ctx context.Context, cmd *cli.Command) error {
+	if err := a.initPolicyBackend(config.Load()); err != nil {
+		return err
+	}
+	defer a.closeBackend()
+
+	policies, err := rbac.ListPolicies(rbac.Policy{
+		Subject: cmd.String("sub"),
+		Domain:  cmd.String("dom"),
+		Object:  cmd.String("obj"),
+		Action:  cmd.String("act"),
+		Effect:  cmd.String("eft"),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range policies {
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\t%d\n", policy.Subject, policy.Domain, policy.Object, policy.Action, policy.Effect, policy.Priority)
+	}
+	return nil
+}
+
+func (a *Action) addPolicy(ctx context.Context, cmd *cli.Command) error {
+	rule, grouping, err := policyRule(cmd)
+	if err != nil {
+		return err
+	}
+	if err := a.initPolicyBackend(config.Load()); err != nil {
+		return err
+	}
+	defer a.closeBackend()
+
+	var added int
+	if grouping {
+		added, err = rbac.AddGroupingPolicies([][]string{rule})
+	} else {
+		added, err = rbac.AddPolicies([][]string{rule})
+	}
+	if err != nil {
+		return err
+	}
+
+	if added == 0 {
+		slog.Info("规则已存在", "rule", rule)
+	} else {
+		slog.Info("规则已添加", "rule", rule)
+	}
+	return nil
+}
+
+func (a *Action) removePolicy(ctx context.Context, cmd *cli.Command) error {
+	rule, grouping, err := policyRule(cmd)
+	if err != nil {
+		return err
+	}
+	if err := a.initPolicyBackend(config.Load()); err != nil {
+		return err
+	}
+	defer a.closeBackend()
+
+	var removed int
+	if grouping {
+		removed, err = rbac.RemoveGroupingPolicies([][]string{rule})
+	} else {
+		removed, err = rbac.RemovePolicies([][]string{rule})
+	}
+	if err != nil {
+		return err
+	}
+
+	if removed == 0 {
+		return fmt.Errorf("规则不存在: %v", rule)
+	}
+	slog.Info("规则已删除", "rule", rule)
+	return nil
+}
+
+func (a *Action) importPolicies(ctx context.Context, cmd *cli.Command) error {
+	path := cmd.Args().First()
+	if path == "" {
+		return errors.New("请指定导入文件")
+	}
+
+	set, err := rbac.LoadPolicyFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := a.initPolicyBackend(config.Load()); err != nil {
+		return err
+	}
+	defer a.closeBackend()
+
+	policyService := &service.PolicyService{}
+	result, err := policyService.ImportPolicies(set)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func (a *Action) syncPolicies(ctx context.Context, cmd *cli.Command) error {
+	cfg := config.Load()
+	path := cmd.String("file")
+	if path == "" {
+		path = cfg.Casbin.PolicyFile
+	}
+
+	set, err := rbac.LoadPolicyFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := a.initPolicyBackend(cfg); err != nil {
+		return err
+	}
+	defer a.closeBackend()
+
+	diff, err := rbac.DiffPolicies(set, cmd.Bool("prune-roles"))
+	if err != nil {
+		return err
+	}
+	if err := diff.Print(os.Stdout); err != nil {
+		return err
+	}
+
+	if diff.Empty() || !cmd.Bool("apply") {
+		return nil
+	}
+	if err := rbac.ApplyDiff(diff); err != nil {
+		return err
+	}
+	slog.Info("策略已按文件同步", "file", path)
+	return nil
+}
+
+func (a *Action) exportPolicies(ctx context.Context, cmd *cli.Command) error {
+	var out io.Writer = os.Stdout
+	if path := cmd.String("output"); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if cmd.Bool("defaults") {
+		if _, err := fmt.Fprintln(out, defaultPolicyHeader); err != nil {
+			return err
+		}
+		return rbac.DefaultPolicies().Write(out)
+	}
+
+	if err := a.initPolicyBackend(config.Load()); err != nil {
+		return err
+	}
+	defer a.closeBackend()
+
+	policyService := &service.PolicyService{}
+	return policyService.ExportPolicies(out)
+}
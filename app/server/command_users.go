@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"github.com/urfave/cli/v3"
+	"gorm.io/gorm/logger"
+)
+
+// usersCommand 用户管理命令
+var usersCommand = &cli.Command{
+	Name:  "users",
+	Usage: "用户管理",
+	Commands: []*cli.Command{
+		{
+			Name:      "import",
+			Usage:     "从 CSV 或 JSON 文件批量导入用户",
+			ArgsUsage: "<file>",
+			Action:    action.importUsers,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "文件格式 csv|json，默认根据扩展名判断",
+				},
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "仅校验，不写入数据库",
+				},
+				&cli.BoolFlag{
+					Name:  "partial",
+					Usage: "跳过出错的行，提交其余行",
+				},
+			},
+		},
+		{
+			Name:   "export",
+			Usage:  "导出用户为 CSV 或 JSON",
+			Action: action.exportUsers,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "导出格式 csv|json",
+					Value: service.FormatCSV,
+				},
+				&cli.StringFlag{
+					Name:    "output",
+					Usage:   "输出文件，默认输出到标准输出",
+					Aliases: []string{"o"},
+				},
+			},
+		},
+	},
+}
+
+func (a *Action) importUsers(ctx context.Context, cmd *cli.Command) error {
+	path := cmd.Args().First()
+	if path == "" {
+		return errors.New("请指定导入文件")
+	}
+
+	format := cmd.String("format")
+	if format == "" {
+		format = service.DetectFormat(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	users, err := service.ParseImportUsers(file, format)
+	if err != nil {
+		return err
+	}
+
+	if err := a.initBackend(config.Load()); err != nil {
+		return err
+	}
+	defer a.closeBackend()
+
+	userService := &service.UserService{}
+	result, err := userService.ImportUsers(ctx, users, service.ImportOptions{
+		DryRun:  cmd.Bool("dry-run"),
+		Partial: cmd.Bool("partial"),
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+
+	if !result.DryRun && !result.Committed {
+		return fmt.Errorf("导入失败：%d 行出错，已全部回滚", result.Failed)
+	}
+	return nil
+}
+
+func (a *Action) exportUsers(ctx context.Context, cmd *cli.Command) error {
+	format := cmd.String("format")
+	if format != service.FormatCSV && format != service.FormatJSON {
+		return fmt.Errorf("不支持的导出格式: %s", format)
+	}
+
+	if err := a.initBackend(config.Load()); err != nil {
+		return err
+	}
+	defer a.closeBackend()
+
+	var out io.Writer = os.Stdout
+	if path := cmd.String("output"); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	} else {
+		// GORM 默认日志写到标准输出，导出到标准输出时需静默以免混入 SQL 日志
+		database.DB.Logger = database.DB.Logger.LogMode(logger.Silent)
+	}
+
+	userService := &service.UserService{}
+	if err := userService.ExportUsers(ctx, out, format); err != nil {
+		return err
+	}
+
+	slog.Info("用户导出完成", "format", format)
+	return nil
+}
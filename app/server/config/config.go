@@ -4,24 +4,57 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config 应用配置
 type Config struct {
 	Server     ServerConfig
+	TLS        TLSConfig
 	Database   DatabaseConfig
 	Redis      RedisConfig
 	JWT        JWTConfig
 	Casbin     CasbinConfig
+	Log        LogConfig
+	Metrics    MetricsConfig
+	Tracing    TracingConfig
+	CORS       CORSConfig
+	Security   SecurityConfig
+	Web        WebConfig
+	Debug      DebugConfig
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port         int
-	Mode         string // debug, release, test
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
+	Port            int
+	Mode            string // debug, release, test
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	HealthTimeout   time.Duration // 就绪检查中单项依赖检查的超时
+	ShutdownDelay   time.Duration // 收到退出信号后先让就绪检查失败并等待该时长，再停止接收请求
+	ShutdownTimeout time.Duration // 关闭全部组件的总超时，超时后强制退出
+	HookTimeout     time.Duration // 单个组件启动、停止和重新加载的默认超时
+	Socket          string        // Unix 域套接字路径，为空时不监听；Port 为 0 时只监听套接字
+	SocketMode      os.FileMode   // 套接字文件权限
+	H2C             bool          // 明文连接（TCP 未启用 TLS 时及 Unix 套接字）上接受 HTTP/2，仅供内网使用
+}
+
+// TLSConfig TLS 配置，CertFile 和 KeyFile 都设置时主端口启用 HTTPS 和 HTTP/2
+type TLSConfig struct {
+	CertFile       string
+	KeyFile        string
+	ClientCAFile   string        // 校验客户端证书的 CA，设置后启用 mTLS
+	ClientAuth     string        // request、require、verify-if-given 或 require-and-verify（默认）
+	MinVersion     string        // 1.2 或 1.3
+	ReloadInterval time.Duration // 检查证书文件变化的间隔，为 0 时只在 SIGHUP 时重新加载
+	HTTP3          bool          // 在主端口的 UDP 上同时提供 HTTP/3
+	RedirectAddr   string        // HTTP 重定向到 HTTPS 的监听地址（如 :80），为空时不监听
+}
+
+// Enabled 是否启用 TLS
+func (c *TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
 }
 
 // DatabaseConfig 数据库配置
@@ -35,6 +68,7 @@ type DatabaseConfig struct {
 	MaxOpen  int
 	MaxIdle  int
 	MaxLife  time.Duration
+	SlowSQL  time.Duration // 超过该耗时的 SQL 以 Warn 级别记录，其余 SQL 以 Debug 级别记录
 }
 
 // RedisConfig Redis配置
@@ -46,27 +80,124 @@ type RedisConfig struct {
 	PoolSize int
 }
 
+// LogConfig 日志配置
+type LogConfig struct {
+	Level  string // debug, info, warn, error
+	Format string // text, json
+}
+
+// MetricsConfig Prometheus 指标配置
+type MetricsConfig struct {
+	Enabled bool
+	Path    string // 单独的管理端口上的路径，主端口上固定为 /api/metrics
+	Addr    string // 单独的管理端口监听地址（如 127.0.0.1:9090），不做认证，只应监听回环地址；为空时挂在主端口的 /api/metrics 下并受 Casbin 保护
+	Token   string // 主端口上供抓取程序使用的静态令牌（Authorization: Bearer <token>），持有者无需登录即可读取指标；为空时只能用用户 Token 访问
+}
+
+// DebugConfig 诊断接口配置（pprof、运行时统计、生效配置、日志级别）
+type DebugConfig struct {
+	Enabled bool
+	Addr    string // 单独的监听地址（如 127.0.0.1:6060），不做认证，只应监听回环地址；为空时挂在主端口的 /api/debug 下并受 Casbin 保护
+}
+
+// TracingConfig OpenTelemetry 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool
+	ServiceName string
+	Exporter    string  // otlp、stdout、file
+	Endpoint    string  // OTLP/HTTP 地址（host:port），为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure    bool    // OTLP 不使用 TLS
+	File        string  // file 导出器写入的文件
+	SampleRatio float64 // 根 span 的采样比例，已有上游 trace 时跟随上游的采样决定
+}
+
+// CORSConfig 跨域配置
+type CORSConfig struct {
+	AllowOrigins     []string // 精确来源、子域名通配（https://*.example.com）或 regex: 开头的正则，* 表示任意来源
+	AllowMethods     []string
+	AllowHeaders     []string // * 表示允许预检请求中的任意请求头
+	ExposeHeaders    []string
+	MaxAge           time.Duration // 预检结果的缓存时间
+	AllowCredentials bool
+}
+
+// SecurityConfig 安全响应头配置
+type SecurityConfig struct {
+	HSTSMaxAge            time.Duration // 为 0 时不发送 HSTS，只在 HTTPS 请求上发送
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	FrameOptions          string // DENY 或 SAMEORIGIN，为空时不发送
+	ReferrerPolicy        string
+	PermissionsPolicy     string
+	CSP                   string // Content-Security-Policy，{nonce} 会被替换为每个请求的随机 nonce；为空时不发送
+	CSPReportOnly         bool   // 只报告不拦截（Content-Security-Policy-Report-Only）
+	CSPReportURI          string // 违规报告的接收地址，为空时不追加 report-uri
+}
+
+// WebConfig 前端静态文件配置
+type WebConfig struct {
+	Dir string // 前端构建产物目录（vite build 输出），目录中没有 index.html 时不提供前端页面
+}
+
 // JWTConfig JWT配置
 type JWTConfig struct {
 	Secret     string
 	ExpireTime time.Duration
 	Issuer     string
+
+	// 认证方式：header 只接受 Authorization 请求头，登录返回 token；
+	// cookie 登录写入 HttpOnly Cookie 且不返回 token；both 两者皆有。
+	// cookie、both 模式下请求头和 Cookie 都被接受，Cookie 认证的非安全方法需要 CSRF token
+	Mode           string
+	CookieName     string
+	CookieDomain   string
+	CookiePath     string
+	CookieSecure   bool
+	CookieSameSite string // strict、lax 或 none
+	CSRFCookieName string // 前端可读的 CSRF Cookie，请求时原样放入 CSRFHeader
+	CSRFHeader     string
 }
 
 // CasbinConfig Casbin配置
 type CasbinConfig struct {
-	ModelPath  string
-	PolicyFile string
+	ModelPath         string
+	PolicyFile        string
+	Effect            string        // 策略效果组合方式：allow、deny-override、priority
+	Declarative       bool          // 声明式模式：启动时以 PolicyFile 为准同步策略
+	PruneRoles        bool          // 按策略文件同步时是否删除文件中不存在的角色分配（g 规则）
+	WatcherEnabled    bool          // 是否通过 Redis 在多个副本间同步策略变更
+	WatcherChannel    string        // 策略变更广播的 Redis 频道
+	ReloadInterval    time.Duration // 定时全量加载策略的间隔，0 表示不定时加载
+	SweepInterval     time.Duration // 清理到期临时角色的间隔
+	SyncCatalog       bool          // 启动时将路由登记的权限目录同步到 permissions 表
+	DecisionCacheSize int           // 权限判定 LRU 缓存的容量，0 表示不缓存
 }
 
 // Load 加载配置
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnvAsInt("SERVER_PORT", 8080),
-			Mode:         getEnv("SERVER_MODE", "debug"),
-			ReadTimeout:  time.Duration(getEnvAsInt("SERVER_READ_TIMEOUT", 15)) * time.Second,
-			WriteTimeout: time.Duration(getEnvAsInt("SERVER_WRITE_TIMEOUT", 15)) * time.Second,
+			Port:            getEnvAsInt("SERVER_PORT", 8080),
+			Mode:            getEnv("SERVER_MODE", "debug"),
+			ReadTimeout:     time.Duration(getEnvAsInt("SERVER_READ_TIMEOUT", 15)) * time.Second,
+			WriteTimeout:    time.Duration(getEnvAsInt("SERVER_WRITE_TIMEOUT", 15)) * time.Second,
+			HealthTimeout:   time.Duration(getEnvAsInt("SERVER_HEALTH_TIMEOUT", 2)) * time.Second,
+			ShutdownDelay:   time.Duration(getEnvAsInt("SERVER_SHUTDOWN_DELAY", 5)) * time.Second,
+			ShutdownTimeout: time.Duration(getEnvAsInt("SERVER_SHUTDOWN_TIMEOUT", 30)) * time.Second,
+			HookTimeout:     time.Duration(getEnvAsInt("SERVER_HOOK_TIMEOUT", 10)) * time.Second,
+			Socket:          getEnv("SERVER_SOCKET", ""),
+			SocketMode:      getEnvAsFileMode("SERVER_SOCKET_MODE", 0o660),
+			H2C:             getEnvAsBool("SERVER_H2C", false),
+		},
+		TLS: TLSConfig{
+			CertFile:       getEnv("TLS_CERT_FILE", ""),
+			KeyFile:        getEnv("TLS_KEY_FILE", ""),
+			ClientCAFile:   getEnv("TLS_CLIENT_CA_FILE", ""),
+			ClientAuth:     getEnv("TLS_CLIENT_AUTH", "require-and-verify"),
+			MinVersion:     getEnv("TLS_MIN_VERSION", "1.2"),
+			ReloadInterval: time.Duration(getEnvAsInt("TLS_RELOAD_INTERVAL", 30)) * time.Second,
+			HTTP3:          getEnvAsBool("TLS_HTTP3", false),
+			RedirectAddr:   getEnv("TLS_REDIRECT_ADDR", ""),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -78,6 +209,7 @@ func Load() *Config {
 			MaxOpen:  getEnvAsInt("DB_MAX_OPEN", 25),
 			MaxIdle:  getEnvAsInt("DB_MAX_IDLE", 5),
 			MaxLife:  time.Duration(getEnvAsInt("DB_MAX_LIFE", 300)) * time.Second,
+			SlowSQL:  time.Duration(getEnvAsInt("DB_SLOW_SQL_MS", 200)) * time.Millisecond,
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
@@ -90,10 +222,78 @@ func Load() *Config {
 			Secret:     getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
 			ExpireTime: time.Duration(getEnvAsInt("JWT_EXPIRE_HOURS", 24)) * time.Hour,
 			Issuer:     getEnv("JWT_ISSUER", "vuetify-app"),
+
+			Mode:           getEnv("AUTH_MODE", "header"),
+			CookieName:     getEnv("AUTH_COOKIE_NAME", "session"),
+			CookieDomain:   getEnv("AUTH_COOKIE_DOMAIN", ""),
+			CookiePath:     getEnv("AUTH_COOKIE_PATH", "/"),
+			CookieSecure:   getEnvAsBool("AUTH_COOKIE_SECURE", true),
+			CookieSameSite: getEnv("AUTH_COOKIE_SAMESITE", "strict"),
+			CSRFCookieName: getEnv("AUTH_CSRF_COOKIE_NAME", "csrf_token"),
+			CSRFHeader:     getEnv("AUTH_CSRF_HEADER", "X-CSRF-Token"),
 		},
 		Casbin: CasbinConfig{
-			ModelPath:  getEnv("CASBIN_MODEL_PATH", "./configs/rbac_model.conf"),
-			PolicyFile: getEnv("CASBIN_POLICY_FILE", "./configs/rbac_policy.csv"),
+			ModelPath:         getEnv("CASBIN_MODEL_PATH", "./configs/rbac_model.conf"),
+			PolicyFile:        getEnv("CASBIN_POLICY_FILE", "./configs/rbac_policy.csv"),
+			Effect:            getEnv("CASBIN_EFFECT", "deny-override"),
+			Declarative:       getEnvAsBool("CASBIN_POLICY_DECLARATIVE", false),
+			PruneRoles:        getEnvAsBool("CASBIN_POLICY_PRUNE_ROLES", false),
+			WatcherEnabled:    getEnvAsBool("CASBIN_WATCHER", true),
+			WatcherChannel:    getEnv("CASBIN_WATCHER_CHANNEL", "casbin:policy"),
+			ReloadInterval:    time.Duration(getEnvAsInt("CASBIN_RELOAD_INTERVAL", 300)) * time.Second,
+			SweepInterval:     time.Duration(getEnvAsInt("CASBIN_GRANT_SWEEP_INTERVAL", 60)) * time.Second,
+			SyncCatalog:       getEnvAsBool("PERMISSION_CATALOG_SYNC", true),
+			DecisionCacheSize: getEnvAsInt("CASBIN_DECISION_CACHE_SIZE", 10000),
+		},
+		Log: LogConfig{
+			Level:  getEnv("LOG_LEVEL", "info"),
+			Format: getEnv("LOG_FORMAT", "text"),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getEnvAsBool("METRICS_ENABLED", true),
+			Path:    getEnv("METRICS_PATH", "/metrics"),
+			Addr:    getEnv("METRICS_ADDR", ""),
+			Token:   getEnv("METRICS_TOKEN", ""),
+		},
+		Debug: DebugConfig{
+			Enabled: getEnvAsBool("DEBUG_ENABLED", false),
+			Addr:    getEnv("DEBUG_ADDR", ""),
+		},
+		CORS: CORSConfig{
+			AllowOrigins: getEnvAsSlice("CORS_ALLOW_ORIGINS", []string{"http://localhost:5173", "http://127.0.0.1:5173"}),
+			AllowMethods: getEnvAsSlice("CORS_ALLOW_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+			AllowHeaders: getEnvAsSlice("CORS_ALLOW_HEADERS", []string{
+				"Content-Type", "Accept", "Authorization", "Cache-Control", "X-Requested-With",
+				"X-CSRF-Token", "X-Tenant", "X-Request-ID", "traceparent", "tracestate",
+			}),
+			ExposeHeaders:    getEnvAsSlice("CORS_EXPOSE_HEADERS", []string{"X-Request-ID", "X-Trace-ID"}),
+			MaxAge:           time.Duration(getEnvAsInt("CORS_MAX_AGE", 600)) * time.Second,
+			AllowCredentials: getEnvAsBool("CORS_ALLOW_CREDENTIALS", true),
+		},
+		Security: SecurityConfig{
+			HSTSMaxAge:            time.Duration(getEnvAsInt("SECURITY_HSTS_MAX_AGE", 31536000)) * time.Second,
+			HSTSIncludeSubdomains: getEnvAsBool("SECURITY_HSTS_INCLUDE_SUBDOMAINS", true),
+			HSTSPreload:           getEnvAsBool("SECURITY_HSTS_PRELOAD", false),
+			FrameOptions:          getEnv("SECURITY_FRAME_OPTIONS", "DENY"),
+			ReferrerPolicy:        getEnv("SECURITY_REFERRER_POLICY", "strict-origin-when-cross-origin"),
+			PermissionsPolicy:     getEnv("SECURITY_PERMISSIONS_POLICY", "camera=(), microphone=(), geolocation=(), payment=(), usb=()"),
+			CSP: getEnv("SECURITY_CSP", "default-src 'self'; script-src 'self' 'nonce-{nonce}'; "+
+				"style-src 'self' 'nonce-{nonce}'; img-src 'self' data:; font-src 'self' data:; connect-src 'self'; "+
+				"object-src 'none'; base-uri 'self'; form-action 'self'"),
+			CSPReportOnly: getEnvAsBool("SECURITY_CSP_REPORT_ONLY", false),
+			CSPReportURI:  getEnv("SECURITY_CSP_REPORT_URI", "/api/csp-report"),
+		},
+		Web: WebConfig{
+			Dir: getEnv("WEB_DIR", "./dist"),
+		},
+		Tracing: TracingConfig{
+			Enabled:     getEnvAsBool("TRACING_ENABLED", false),
+			ServiceName: getEnv("TRACING_SERVICE_NAME", "vuetify-app"),
+			Exporter:    getEnv("TRACING_EXPORTER", "otlp"),
+			Endpoint:    getEnv("TRACING_ENDPOINT", ""),
+			Insecure:    getEnvAsBool("TRACING_INSECURE", true),
+			File:        getEnv("TRACING_FILE", "./traces.jsonl"),
+			SampleRatio: getEnvAsFloat("TRACING_SAMPLE_RATIO", 1),
 		},
 	}
 }
@@ -146,3 +346,53 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsFloat 获取浮点型环境变量，如果不存在则返回默认值
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	for _, k := range []string{EnvPrefix + key, key} {
+		if value := os.Getenv(k); value != "" {
+			if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+				return floatValue
+			}
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsFileMode 获取八进制文件权限环境变量（如 0660），如果不存在则返回默认值
+func getEnvAsFileMode(key string, defaultValue os.FileMode) os.FileMode {
+	for _, k := range []string{EnvPrefix + key, key} {
+		if value := os.Getenv(k); value != "" {
+			if mode, err := strconv.ParseUint(value, 8, 32); err == nil {
+				return os.FileMode(mode)
+			}
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsSlice 获取逗号分隔的列表环境变量，如果不存在则返回默认值
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvAsBool 获取布尔型环境变量，如果不存在则返回默认值
+func getEnvAsBool(key string, defaultValue bool) bool {
+	for _, k := range []string{EnvPrefix + key, key} {
+		if value := os.Getenv(k); value != "" {
+			if boolValue, err := strconv.ParseBool(value); err == nil {
+				return boolValue
+			}
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// redactedValue 敏感配置项的占位符
+const redactedValue = "******"
+
+// sensitiveFields 字段名包含这些词的配置项视为敏感信息
+var sensitiveFields = []string{"password", "secret", "token"}
+
+// Redacted 将配置转换为便于查看的 map：敏感项替换为占位符，时长和文件权限转为可读的字符串
+func (c *Config) Redacted() map[string]any {
+	return redactStruct(reflect.ValueOf(*c))
+}
+
+func redactStruct(v reflect.Value) map[string]any {
+	t := v.Type()
+	out := make(map[string]any, t.NumField())
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i)
+		if isSensitive(field.Name) {
+			if !value.IsZero() {
+				out[field.Name] = redactedValue
+			} else {
+				out[field.Name] = ""
+			}
+			continue
+		}
+		out[field.Name] = redactValue(value)
+	}
+	return out
+}
+
+func redactValue(v reflect.Value) any {
+	switch value := v.Interface().(type) {
+	case time.Duration:
+		return value.String()
+	case os.FileMode:
+		return fmt.Sprintf("%#o", uint32(value))
+	}
+	if v.Kind() == reflect.Struct {
+		return redactStruct(v)
+	}
+	return v.Interface()
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range sensitiveFields {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
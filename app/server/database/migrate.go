@@ -1,28 +1,128 @@
 package database
 
 import (
+	"context"
 	"log/slog"
+	"sync/atomic"
 
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
+// models 需要迁移的模型
+var models = []any{
+	&model.User{},
+	&model.Role{},
+	&model.Permission{},
+	&model.CasbinRule{},
+	&model.Tenant{},
+	&model.TenantMember{},
+	&model.Group{},
+	&model.RoleGrant{},
+	&model.AuditLog{},
+}
+
 // AutoMigrate 自动迁移数据库
 func AutoMigrate() error {
 	slog.Info("开始数据库迁移...")
-	
-	err := DB.AutoMigrate(
-		&model.User{},
-		&model.Role{},
-		&model.Permission{},
-		&model.CasbinRule{},
-	)
-	
+
+	err := DB.AutoMigrate(models...)
+
 	if err != nil {
 		slog.Error("数据库迁移失败", "error", err)
 		return err
 	}
-	
+
+	if err := dropLegacyIndexes(); err != nil {
+		slog.Error("删除旧索引失败", "error", err)
+		return err
+	}
+
 	slog.Info("数据库迁移完成")
 	return nil
 }
 
+// setupJoinTables 为 user_roles 注册自定义关联模型，以便保存角色的有效期
+func setupJoinTables() error {
+	if err := DB.SetupJoinTable(&model.User{}, "Roles", &model.UserRole{}); err != nil {
+		return err
+	}
+	return DB.SetupJoinTable(&model.Role{}, "Users", &model.UserRole{})
+}
+
+// legacyIndexes 不区分软删除的旧唯一索引，已由带 WHERE deleted_at IS NULL 的部分索引取代
+var legacyIndexes = []struct {
+	model any
+	name  string
+}{
+	{&model.User{}, "idx_users_username"},
+	{&model.User{}, "idx_users_email"},
+	{&model.Permission{}, "idx_permissions_name"},
+}
+
+// dropLegacyIndexes 删除不区分软删除的旧唯一索引
+// 旧索引会让已软删除用户的用户名/邮箱无法被重新注册，已软删除权限的名称也无法被权限目录同步重新创建
+func dropLegacyIndexes() error {
+	migrator := DB.Migrator()
+	for _, index := range legacyIndexes {
+		if !migrator.HasIndex(index.model, index.name) {
+			continue
+		}
+		if err := migrator.DropIndex(index.model, index.name); err != nil {
+			return err
+		}
+		slog.Info("已删除旧索引", "index", index.name)
+	}
+	return nil
+}
+
+// migrated 已确认数据库结构与模型一致，之后不再重复检查
+var migrated atomic.Bool
+
+// PendingMigrations 返回数据库中缺少的表和列（table 或 table.column），为空表示迁移已是最新
+// 只检查表和列是否存在，不比较类型和索引；确认一致后结果会被缓存
+func PendingMigrations(ctx context.Context) ([]string, error) {
+	if migrated.Load() {
+		return nil, nil
+	}
+
+	db := DB.WithContext(ctx)
+	migrator := db.Migrator()
+	var pending []string
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return nil, err
+		}
+		tables := []*schema.Schema{stmt.Schema}
+		for _, rel := range stmt.Schema.Relationships.Relations {
+			if rel.JoinTable != nil {
+				tables = append(tables, rel.JoinTable)
+			}
+		}
+
+		for _, table := range tables {
+			if !migrator.HasTable(table.Table) {
+				pending = append(pending, table.Table)
+				continue
+			}
+			for _, field := range table.Fields {
+				if field.DBName == "" || field.IgnoreMigration {
+					continue
+				}
+				if !migrator.HasColumn(table.Table, field.DBName) {
+					pending = append(pending, table.Table+"."+field.DBName)
+				}
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(pending) == 0 {
+		migrated.Store(true)
+	}
+	return pending, nil
+}
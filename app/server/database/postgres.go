@@ -7,7 +7,7 @@ import (
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	otelgorm "gorm.io/plugin/opentelemetry/tracing"
 )
 
 var DB *gorm.DB
@@ -16,9 +16,9 @@ var DB *gorm.DB
 func InitPostgreSQL(cfg *config.DatabaseConfig) error {
 	var err error
 	
-	// GORM 配置
+	// GORM 配置，SQL 日志写入请求范围的 logger
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: newGormLogger(cfg.SlowSQL),
 	}
 
 	// 连接数据库
@@ -27,6 +27,22 @@ func InitPostgreSQL(cfg *config.DatabaseConfig) error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// 注册链路追踪插件：通过 DB.WithContext(ctx) 执行的 SQL 会成为请求 span 的子 span
+	// 不记录 SQL 参数，避免密码等敏感数据进入链路数据
+	if err := DB.Use(otelgorm.NewPlugin(otelgorm.WithoutMetrics(), otelgorm.WithoutQueryVariables())); err != nil {
+		return fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
+	// 注册租户数据隔离插件
+	if err := DB.Use(tenantScope{}); err != nil {
+		return fmt.Errorf("failed to register tenant scope: %w", err)
+	}
+
+	// 注册 user_roles 的自定义关联模型
+	if err := setupJoinTables(); err != nil {
+		return fmt.Errorf("failed to setup join tables: %w", err)
+	}
+
 	// 获取底层的 sql.DB
 	sqlDB, err := DB.DB()
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -20,6 +21,11 @@ func InitRedis(cfg *config.RedisConfig) error {
 		PoolSize: cfg.PoolSize,
 	})
 
+	// 为 Redis 命令创建子 span
+	if err := redisotel.InstrumentTracing(RDB); err != nil {
+		return fmt.Errorf("failed to instrument redis tracing: %w", err)
+	}
+
 	// 测试连接
 	ctx := context.Background()
 	if err := RDB.Ping(ctx).Err(); err != nil {
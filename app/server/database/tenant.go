@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantField 租户数据的标识字段，含该字段的模型视为租户数据
+const tenantField = "TenantID"
+
+type tenantKey struct{}
+
+// WithTenant 在上下文中设置当前租户
+// 通过 DB.WithContext(ctx) 执行的查询、更新、删除会自动追加 tenant_id 条件，创建时自动填充 TenantID
+func WithTenant(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext 获取上下文中的当前租户
+func TenantFromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	tenantID, ok := ctx.Value(tenantKey{}).(uint)
+	return tenantID, ok && tenantID != 0
+}
+
+// tenantScope 租户数据隔离插件
+type tenantScope struct{}
+
+// Name 插件名称
+func (tenantScope) Name() string {
+	return "tenant_scope"
+}
+
+// Initialize 注册回调
+func (tenantScope) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tenant:create", assignTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", filterTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", filterTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenant:delete", filterTenant); err != nil {
+		return err
+	}
+	return callbacks.Row().Before("gorm:row").Register("tenant:row", filterTenant)
+}
+
+// filterTenant 为租户数据追加 tenant_id 条件
+func filterTenant(db *gorm.DB) {
+	tenantID, ok := TenantFromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID},
+	}})
+}
+
+// assignTenant 创建租户数据时填充 TenantID
+func assignTenant(db *gorm.DB) {
+	tenantID, ok := TenantFromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	value := db.Statement.ReflectValue
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := field.Set(ctx, reflect.Indirect(value.Index(i)), tenantID); err != nil {
+				_ = db.AddError(err)
+				return
+			}
+		}
+	case reflect.Struct:
+		if err := field.Set(ctx, value, tenantID); err != nil {
+			_ = db.AddError(err)
+		}
+	}
+}
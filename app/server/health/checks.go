@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+)
+
+// DefaultTimeout 单项检查的默认超时
+const DefaultTimeout = 2 * time.Second
+
+// Default 全局检查器，已登记 Postgres、Redis、Casbin 和迁移检查
+var Default = NewChecker(DefaultTimeout)
+
+func init() {
+	Default.Register(Check{Name: "postgres", Run: checkPostgres})
+	Default.Register(Check{Name: "redis", Run: checkRedis})
+	Default.Register(Check{Name: "casbin", Run: checkCasbin})
+	Default.Register(Check{Name: "migrations", Run: checkMigrations})
+}
+
+// SetTimeout 设置未单独指定超时的检查使用的默认超时
+func (c *Checker) SetTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = timeout
+}
+
+func checkPostgres(ctx context.Context) error {
+	if database.DB == nil {
+		return errors.New("数据库未初始化")
+	}
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func checkRedis(ctx context.Context) error {
+	if database.RDB == nil {
+		return errors.New("redis 未初始化")
+	}
+	return database.RDB.Ping(ctx).Err()
+}
+
+func checkCasbin(ctx context.Context) error {
+	if !rbac.PolicyLoaded() {
+		return errors.New("策略未加载")
+	}
+	return nil
+}
+
+func checkMigrations(ctx context.Context) error {
+	if database.DB == nil {
+		return errors.New("数据库未初始化")
+	}
+	pending, err := database.PendingMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	const limit = 5
+	shown := pending[:min(len(pending), limit)]
+	more := ""
+	if len(pending) > limit {
+		more = fmt.Sprintf(" 等 %d 项", len(pending))
+	}
+	return fmt.Errorf("数据库迁移未完成，缺少 %s%s", strings.Join(shown, ", "), more)
+}
@@ -0,0 +1,162 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/version"
+)
+
+// ErrShuttingDown 服务正在关闭
+var ErrShuttingDown = errors.New("服务正在关闭")
+
+// 检查结果状态
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check 一项就绪检查
+type Check struct {
+	Name    string
+	Timeout time.Duration // 为 0 时使用 Checker 的默认超时
+	Run     func(ctx context.Context) error
+}
+
+// CheckResult 单项检查结果
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Build 构建信息
+type Build struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	Info      string `json:"info"`
+}
+
+// Report 健康检查报告
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+	Build  Build         `json:"build"`
+}
+
+// Checker 管理就绪检查和关闭状态
+type Checker struct {
+	timeout      time.Duration
+	mu           sync.RWMutex
+	checks       []Check
+	shuttingDown atomic.Bool
+}
+
+// NewChecker 创建检查器，timeout 为未单独指定超时的检查使用的默认超时
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Register 登记一项就绪检查
+func (c *Checker) Register(check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check)
+}
+
+// SetShuttingDown 标记服务正在关闭，之后就绪检查始终失败，使负载均衡摘除本实例
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Live 存活检查，只要进程能处理请求即成功
+func (c *Checker) Live() *Report {
+	return &Report{Status: StatusOK, Build: buildInfo()}
+}
+
+// Ready 并发执行全部就绪检查，每项检查有独立的超时；任一失败或正在关闭时整体失败
+func (c *Checker) Ready(ctx context.Context) *Report {
+	c.mu.RLock()
+	checks := append([]Check(nil), c.checks...)
+	timeout := c.timeout
+	c.mu.RUnlock()
+
+	report := &Report{
+		Status: StatusOK,
+		Checks: make([]CheckResult, len(checks)),
+		Build:  buildInfo(),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, check, timeout)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	if c.shuttingDown.Load() {
+		report.Status = StatusFail
+		report.Checks = append(report.Checks, CheckResult{
+			Name:     "shutdown",
+			Status:   StatusFail,
+			Error:    ErrShuttingDown.Error(),
+			Duration: "0s",
+		})
+	}
+	return report
+}
+
+// run 在超时内执行一项检查；检查函数未响应 ctx 时也会在超时后返回
+func run(ctx context.Context, check Check, timeout time.Duration) CheckResult {
+	if check.Timeout > 0 {
+		timeout = check.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Name:     check.Name,
+		Status:   StatusOK,
+		Duration: time.Since(start).Round(time.Microsecond).String(),
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func buildInfo() Build {
+	return Build{
+		Version:   version.AppVersion,
+		Commit:    version.GitCommit,
+		BuildTime: version.BuildTime,
+		Info:      version.GetBuildInfo(),
+	}
+}
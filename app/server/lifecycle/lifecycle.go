@@ -0,0 +1,268 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Hook 一个组件的启动、停止和重新加载钩子，各钩子均可为空
+type Hook struct {
+	Name      string
+	DependsOn []string      // 依赖的组件，先于本组件启动、晚于本组件停止
+	Timeout   time.Duration // 启动和停止各自的超时，为 0 时使用 Manager 的默认超时，为负数时不限时
+
+	// OnStart 的 ctx 只在启动期间有效，后台任务应使用 Manager.Context
+	OnStart  func(ctx context.Context) error
+	OnStop   func(ctx context.Context) error
+	OnReload func(ctx context.Context) error
+}
+
+// Manager 按依赖顺序启动组件，按相反顺序停止，并提供贯穿整个进程的根 context
+type Manager struct {
+	timeout time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	hooks   []Hook
+	started []Hook
+
+	failed   chan error
+	failOnce sync.Once
+}
+
+// New 创建管理器，timeout 为未单独指定超时的钩子使用的默认超时；
+// 根 context 派生自 parent，在全部组件停止后（或停止超时时）取消
+func New(parent context.Context, timeout time.Duration) *Manager {
+	ctx, cancel := context.WithCancel(parent)
+	return &Manager{
+		timeout: timeout,
+		ctx:     ctx,
+		cancel:  cancel,
+		failed:  make(chan error, 1),
+	}
+}
+
+// Append 登记一个组件，必须在 Start 之前调用
+func (m *Manager) Append(hook Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// Context 根 context，后台任务和请求的 context 应由它派生
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// Fail 报告组件运行期间的致命错误，Wait 收到后开始关闭；只保留第一个错误
+func (m *Manager) Fail(err error) {
+	m.failOnce.Do(func() {
+		m.failed <- err
+	})
+}
+
+// Start 按依赖顺序启动全部组件；任一组件失败时按相反顺序停止已启动的组件并返回错误
+func (m *Manager) Start() error {
+	m.mu.Lock()
+	ordered, err := sortHooks(m.hooks)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range ordered {
+		if hook.OnStart != nil {
+			start := time.Now()
+			if err := m.call(m.ctx, hook, hook.OnStart); err != nil {
+				slog.Error("组件启动失败", "component", hook.Name, "error", err)
+				stopCtx, cancel := context.WithTimeout(context.Background(), m.timeout*time.Duration(len(m.started)+1))
+				defer cancel()
+				return errors.Join(fmt.Errorf("启动 %s: %w", hook.Name, err), m.Stop(stopCtx))
+			}
+			slog.Debug("组件已启动", "component", hook.Name, "duration", time.Since(start))
+		}
+		m.mu.Lock()
+		m.started = append(m.started, hook)
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// Stop 按启动的相反顺序停止已启动的组件，每个组件有独立的超时且不超过 ctx 的期限；
+// 某个组件停止失败不影响其余组件。全部完成或 ctx 结束时取消根 context
+func (m *Manager) Stop(ctx context.Context) error {
+	stopCancel := context.AfterFunc(ctx, m.cancel)
+	defer stopCancel()
+	defer m.cancel()
+
+	m.mu.Lock()
+	started := m.started
+	m.started = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		hook := started[i]
+		if hook.OnStop == nil {
+			continue
+		}
+		start := time.Now()
+		if err := m.call(ctx, hook, hook.OnStop); err != nil {
+			slog.Error("组件停止异常", "component", hook.Name, "error", err)
+			errs = append(errs, fmt.Errorf("停止 %s: %w", hook.Name, err))
+			continue
+		}
+		slog.Debug("组件已停止", "component", hook.Name, "duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// Reload 按启动顺序调用已启动组件的重新加载钩子，某个组件失败不影响其余组件
+func (m *Manager) Reload(ctx context.Context) error {
+	m.mu.Lock()
+	started := append([]Hook(nil), m.started...)
+	m.mu.Unlock()
+
+	var errs []error
+	for _, hook := range started {
+		if hook.OnReload == nil {
+			continue
+		}
+		if err := m.call(ctx, hook, hook.OnReload); err != nil {
+			slog.Error("组件重新加载失败", "component", hook.Name, "error", err)
+			errs = append(errs, fmt.Errorf("重新加载 %s: %w", hook.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Wait 阻塞直到收到 SIGINT/SIGTERM、组件报告致命错误或父 context 结束；
+// 期间收到 SIGHUP 时调用 Reload。返回组件报告的错误
+func (m *Manager) Wait() error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case sig := <-signals:
+			if sig != syscall.SIGHUP {
+				slog.Info("收到退出信号", "signal", sig.String())
+				return nil
+			}
+			slog.Info("收到 SIGHUP，重新加载配置")
+			if err := m.Reload(m.ctx); err != nil {
+				slog.Error("重新加载未全部成功", "error", err)
+			} else {
+				slog.Info("重新加载完成")
+			}
+		case err := <-m.failed:
+			slog.Error("组件异常退出", "error", err)
+			return err
+		case <-m.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Run 启动全部组件并等待退出，然后在 shutdownTimeout 内停止全部组件
+func (m *Manager) Run(shutdownTimeout time.Duration) error {
+	if err := m.Start(); err != nil {
+		return err
+	}
+	runErr := m.Wait()
+
+	slog.Info("正在关闭...", "timeout", shutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	stopErr := m.Stop(ctx)
+	if stopErr == nil {
+		slog.Info("已全部关闭")
+	}
+	return errors.Join(runErr, stopErr)
+}
+
+// call 在组件超时内执行钩子；钩子未响应 ctx 时也会在超时后返回
+func (m *Manager) call(parent context.Context, hook Hook, fn func(context.Context) error) error {
+	timeout := hook.Timeout
+	if timeout == 0 {
+		timeout = m.timeout
+	}
+	if timeout < 0 {
+		return fn(parent)
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sortHooks 按依赖关系排序，无依赖关系的组件保持登记顺序
+func sortHooks(hooks []Hook) ([]Hook, error) {
+	index := make(map[string]int, len(hooks))
+	for i, hook := range hooks {
+		if _, ok := index[hook.Name]; ok {
+			return nil, fmt.Errorf("组件 %s 重复登记", hook.Name)
+		}
+		index[hook.Name] = i
+	}
+	for _, hook := range hooks {
+		for _, dep := range hook.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("组件 %s 依赖的 %s 未登记", hook.Name, dep)
+			}
+		}
+	}
+
+	ordered := make([]Hook, 0, len(hooks))
+	done := make([]bool, len(hooks))
+	for len(ordered) < len(hooks) {
+		progressed := false
+		for i, hook := range hooks {
+			if done[i] || !depsDone(hook, index, done) {
+				continue
+			}
+			done[i] = true
+			ordered = append(ordered, hook)
+			progressed = true
+			break
+		}
+		if !progressed {
+			var pending []string
+			for i, hook := range hooks {
+				if !done[i] {
+					pending = append(pending, hook.Name)
+				}
+			}
+			return nil, fmt.Errorf("组件之间存在循环依赖: %v", pending)
+		}
+	}
+	return ordered, nil
+}
+
+func depsDone(hook Hook, index map[string]int, done []bool) bool {
+	for _, dep := range hook.DependsOn {
+		if !done[index[dep]] {
+			return false
+		}
+	}
+	return true
+}
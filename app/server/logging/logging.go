@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// level 全局日志级别，运行中可通过 SetLevel 调整
+var level = new(slog.LevelVar)
+
+// Init 按配置创建默认 logger（slog.Default）
+func Init(cfg *config.LogConfig) error {
+	if err := SetLevel(cfg.Level); err != nil {
+		return err
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return fmt.Errorf("未知的日志格式: %s（可选 text、json）", cfg.Format)
+	}
+	slog.SetDefault(slog.New(traceHandler{handler}))
+	return nil
+}
+
+// traceHandler 为带 context 记录的日志（InfoContext 等）追加当前 span 的 trace_id 和 span_id
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, record slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
+
+// SetLevel 设置全局日志级别：debug、info、warn、error
+func SetLevel(name string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("未知的日志级别: %s（可选 debug、info、warn、error）", name)
+	}
+	level.Set(l)
+	return nil
+}
+
+// Level 返回当前的全局日志级别
+func Level() slog.Level {
+	return level.Level()
+}
+
+type loggerKey struct{}
+
+// NewContext 将请求范围的 logger 存入 ctx
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext 返回 ctx 中的 logger，没有时返回默认 logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// With 在 ctx 中的 logger 上追加属性，返回新的 ctx
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
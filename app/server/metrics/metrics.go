@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/version"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry 应用指标的注册表，包含 Go 运行时和进程指标
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests HTTP 请求数，按方法、路由模板和状态码统计
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP 请求总数",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration HTTP 请求耗时
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP 请求耗时（秒）",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// HTTPInFlight 正在处理的 HTTP 请求数
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "正在处理的 HTTP 请求数",
+	})
+
+	// AuthzDecisions 权限判定次数，result 为 allow、deny 或 error
+	AuthzDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "casbin_decisions_total",
+		Help: "CasbinAuth 中间件的权限判定次数",
+	}, []string{"result"})
+
+	// Logins 登录次数，result 为 success 或失败原因
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "登录次数",
+	}, []string{"result"})
+
+	// CSPViolations 浏览器上报的 CSP 违规次数，按违规指令统计
+	CSPViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "csp_violations_total",
+		Help: "浏览器上报的 CSP 违规次数",
+	}, []string{"directive"})
+
+	buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "app_build_info",
+		Help: "构建信息，值恒为 1",
+	}, []string{"version", "commit", "build_time", "go_version"})
+)
+
+// 权限判定结果
+const (
+	AuthzAllow = "allow"
+	AuthzDeny  = "deny"
+	AuthzError = "error"
+)
+
+// 登录结果
+const (
+	LoginSuccess            = "success"
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginDisabled           = "disabled"
+	LoginTenantDenied       = "tenant_denied"
+	LoginError              = "error"
+)
+
+func init() {
+	buildInfo.WithLabelValues(version.AppVersion, version.GitCommit, version.BuildTime, runtime.Version()).Set(1)
+
+	// 预先创建各结果的序列，使尚未发生的结果也以 0 输出
+	for _, result := range []string{AuthzAllow, AuthzDeny, AuthzError} {
+		AuthzDecisions.WithLabelValues(result)
+	}
+	for _, result := range []string{LoginSuccess, LoginInvalidCredentials, LoginDisabled, LoginTenantDenied, LoginError} {
+		Logins.WithLabelValues(result)
+	}
+
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		HTTPInFlight,
+		AuthzDecisions,
+		Logins,
+		CSPViolations,
+		buildInfo,
+		newRedisCollector(),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "casbin_policy_version",
+			Help: "本节点已应用的策略版本",
+		}, func() float64 { return float64(rbac.PolicyVersion()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "casbin_decision_cache_hits_total",
+			Help: "权限判定缓存命中次数",
+		}, func() float64 { return float64(rbac.GetCacheStats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "casbin_decision_cache_misses_total",
+			Help: "权限判定缓存未命中次数",
+		}, func() float64 { return float64(rbac.GetCacheStats().Misses) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "casbin_decision_cache_invalidations_total",
+			Help: "策略变更导致权限判定缓存清空的次数",
+		}, func() float64 { return float64(rbac.GetCacheStats().Invalidations) }),
+	)
+}
+
+// Handler 返回输出全部指标的 HTTP 处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// LoginAttempt 记录一次登录结果
+func LoginAttempt(result string) {
+	Logins.WithLabelValues(result).Inc()
+}
+
+// AuthzDecision 记录一次权限判定结果
+func AuthzDecision(result string) {
+	AuthzDecisions.WithLabelValues(result).Inc()
+}
+
+// CSPViolation 记录一次 CSP 违规上报
+func CSPViolation(directive string) {
+	CSPViolations.WithLabelValues(directive).Inc()
+}
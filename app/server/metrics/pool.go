@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterDatabase 注册 database.DB 连接池的指标（go_sql_* ，db_name="postgres"），需在 InitPostgreSQL 之后调用
+func RegisterDatabase() error {
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return err
+	}
+	err = Registry.Register(collectors.NewDBStatsCollector(sqlDB, "postgres"))
+	if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		return nil
+	}
+	return err
+}
+
+// redisCollector 采集 database.RDB 连接池的统计，Redis 未初始化时不输出
+type redisCollector struct {
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	waits      *prometheus.Desc
+	waitTime   *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+	staleConns *prometheus.Desc
+}
+
+func newRedisCollector() *redisCollector {
+	return &redisCollector{
+		hits:       prometheus.NewDesc("redis_pool_hits_total", "从连接池取到空闲连接的次数", nil, nil),
+		misses:     prometheus.NewDesc("redis_pool_misses_total", "连接池中没有空闲连接的次数", nil, nil),
+		timeouts:   prometheus.NewDesc("redis_pool_timeouts_total", "等待连接超时的次数", nil, nil),
+		waits:      prometheus.NewDesc("redis_pool_waits_total", "等待空闲连接的次数", nil, nil),
+		waitTime:   prometheus.NewDesc("redis_pool_wait_duration_seconds_total", "等待空闲连接的总耗时（秒）", nil, nil),
+		totalConns: prometheus.NewDesc("redis_pool_conns", "连接池中的连接数", nil, nil),
+		idleConns:  prometheus.NewDesc("redis_pool_idle_conns", "连接池中的空闲连接数", nil, nil),
+		staleConns: prometheus.NewDesc("redis_pool_stale_conns_total", "被移除的过期连接数", nil, nil),
+	}
+}
+
+func (c *redisCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.waits
+	ch <- c.waitTime
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.staleConns
+}
+
+func (c *redisCollector) Collect(ch chan<- prometheus.Metric) {
+	if database.RDB == nil {
+		return
+	}
+	stats := database.RDB.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.waits, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitTime, prometheus.CounterValue, float64(stats.WaitDurationNs)/1e9)
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(stats.StaleConns))
+}
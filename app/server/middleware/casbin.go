@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/metrics"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"gorm.io/gorm"
 )
 
 // CasbinAuth Casbin权限验证中间件
@@ -21,38 +24,79 @@ func CasbinAuth() gin.HandlerFunc {
 			return
 		}
 
-		// 获取请求资源和操作
+		// 获取请求资源和操作，以及当前租户域（由 Tenant 中间件设置）
 		resource := c.Request.URL.Path
 		action := c.Request.Method
+		domain := c.GetString("tenant")
+		if domain == "" {
+			domain = rbac.GlobalDomain
+		}
 
-		// 检查权限（任一角色有权限即可）
-		userRoles := roles.([]string)
-		hasPermission := false
+		req := &rbac.Request{
+			Roles:  roles.([]string),
+			Domain: domain,
+			Object: resource,
+			Action: action,
+			User: rbac.Subject{
+				ID:       c.GetUint("user_id"),
+				Username: c.GetString("username"),
+				TenantID: c.GetUint("tenant_id"),
+			},
+		}
 
-		for _, role := range userRoles {
-			ok, err := rbac.CheckPermission(role, resource, action)
-			if err != nil {
+		// 路由注册了资源加载器时加载资源属性，供条件策略判定
+		// 资源不存在时只按普通策略判定，放行后才返回 404；否则与无权访问已存在的资源一样返回 403，避免借此探测记录是否存在
+		var missing bool
+		if loader := resourceLoader(c); loader != nil {
+			res, err := loader(c)
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				missing = true
+			case err != nil:
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"code":    500,
-					"message": "权限检查失败",
+					"message": "加载资源失败",
 					"error":   err.Error(),
 				})
 				c.Abort()
 				return
+			default:
+				req.Resource = res
+				c.Set("resource", res)
 			}
+		}
 
-			if ok {
-				hasPermission = true
-				break
-			}
+		// 检查权限（普通策略与条件策略合并判定）
+		hasPermission, err := rbac.AuthorizeRequest(req)
+		if err != nil {
+			metrics.AuthzDecision(metrics.AuthzError)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "权限检查失败",
+				"error":   err.Error(),
+			})
+			c.Abort()
+			return
 		}
 
 		if !hasPermission {
+			metrics.AuthzDecision(metrics.AuthzDeny)
 			c.JSON(http.StatusForbidden, gin.H{
 				"code":    403,
 				"message": "无权限访问此资源",
 				"resource": resource,
 				"action":   action,
+				"tenant":   domain,
+			})
+			c.Abort()
+			return
+		}
+		metrics.AuthzDecision(metrics.AuthzAllow)
+
+		if missing {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "资源不存在",
 			})
 			c.Abort()
 			return
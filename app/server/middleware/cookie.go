@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+)
+
+// 认证方式
+const (
+	AuthModeHeader = "header"
+	AuthModeCookie = "cookie"
+	AuthModeBoth   = "both"
+)
+
+// authCfg 认证方式和 Cookie 配置，由 InitJWT 设置
+var authCfg = &config.JWTConfig{Mode: AuthModeHeader}
+
+// validateAuthMode 检查认证方式相关配置
+func validateAuthMode(cfg *config.JWTConfig) error {
+	switch cfg.Mode {
+	case AuthModeHeader, AuthModeCookie, AuthModeBoth:
+	default:
+		return fmt.Errorf("未知的认证方式 %q，可选 header、cookie、both", cfg.Mode)
+	}
+	if cfg.Mode == AuthModeHeader {
+		return nil
+	}
+	if _, err := sameSite(cfg.CookieSameSite); err != nil {
+		return err
+	}
+	if strings.EqualFold(cfg.CookieSameSite, "none") && !cfg.CookieSecure {
+		return fmt.Errorf("SameSite=None 的 Cookie 必须同时设置 Secure")
+	}
+	return nil
+}
+
+func sameSite(name string) (http.SameSite, error) {
+	switch strings.ToLower(name) {
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("未知的 SameSite 取值 %q，可选 strict、lax、none", name)
+	}
+}
+
+// CookieAuthEnabled 登录时是否写入认证 Cookie
+func CookieAuthEnabled() bool {
+	return authCfg.Mode == AuthModeCookie || authCfg.Mode == AuthModeBoth
+}
+
+// TokenInResponse 登录响应中是否返回 token；cookie 模式下 token 只存在于 HttpOnly Cookie 中
+func TokenInResponse() bool {
+	return authCfg.Mode != AuthModeCookie
+}
+
+// SetAuthCookies 写入 HttpOnly 的会话 Cookie 和前端可读的 CSRF Cookie，返回 CSRF token
+func SetAuthCookies(c *gin.Context, token string, expires time.Time) string {
+	csrf := newCSRFToken(token)
+	setCookie(c, authCfg.CookieName, token, expires, true)
+	setCookie(c, authCfg.CSRFCookieName, csrf, expires, false)
+	return csrf
+}
+
+// ClearAuthCookies 删除会话 Cookie 和 CSRF Cookie
+func ClearAuthCookies(c *gin.Context) {
+	setCookie(c, authCfg.CookieName, "", time.Unix(0, 0), true)
+	setCookie(c, authCfg.CSRFCookieName, "", time.Unix(0, 0), false)
+}
+
+func setCookie(c *gin.Context, name, value string, expires time.Time, httpOnly bool) {
+	mode, _ := sameSite(authCfg.CookieSameSite)
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     authCfg.CookiePath,
+		Domain:   authCfg.CookieDomain,
+		Expires:  expires,
+		Secure:   authCfg.CookieSecure,
+		HttpOnly: httpOnly,
+		SameSite: mode,
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(c.Writer, cookie)
+}
+
+// newCSRFToken 生成与会话绑定的 CSRF token：随机值 + HMAC(随机值, 会话 token)，
+// 其他会话的 token 或攻击者写入的 Cookie 都无法通过校验
+func newCSRFToken(session string) string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	nonce := base64.RawURLEncoding.EncodeToString(b)
+	return nonce + "." + csrfSignature(nonce, session)
+}
+
+func csrfSignature(nonce, session string) string {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte("csrf|" + nonce + "|" + session))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyCSRF 双重提交校验：请求头中的 token 必须与 CSRF Cookie 一致，且签名与当前会话匹配
+func verifyCSRF(c *gin.Context, session string) bool {
+	header := c.GetHeader(authCfg.CSRFHeader)
+	cookie, err := c.Cookie(authCfg.CSRFCookieName)
+	if header == "" || err != nil || subtle.ConstantTimeCompare([]byte(header), []byte(cookie)) != 1 {
+		return false
+	}
+	nonce, signature, ok := strings.Cut(header, ".")
+	if !ok {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(csrfSignature(nonce, session)))
+}
+
+// safeMethod 不改变状态、无需 CSRF 校验的方法
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -1,23 +1,155 @@
 package middleware
 
 import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
 	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
 )
 
+// originMatcher 判断 Origin 是否在允许列表中
+type originMatcher func(origin string) bool
+
+// newOriginMatcher 解析一条允许的来源：
+// 精确匹配（https://app.example.com）、子域名通配（https://*.example.com，不含 example.com 本身）
+// 或以 regex: 开头的正则（regex:^https://.*\.example\.com$）；* 表示任意来源
+func newOriginMatcher(pattern string) (originMatcher, error) {
+	switch {
+	case pattern == "*":
+		return func(string) bool { return true }, nil
+	case strings.HasPrefix(pattern, "regex:"):
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, "regex:"))
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	case strings.Contains(pattern, "://*."):
+		scheme, host, _ := strings.Cut(pattern, "://*.")
+		prefix, suffix := scheme+"://", "."+strings.ToLower(host)
+		return func(origin string) bool {
+			origin = strings.ToLower(origin)
+			return strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) &&
+				len(origin) > len(prefix)+len(suffix)
+		}, nil
+	default:
+		exact := strings.ToLower(strings.TrimSuffix(pattern, "/"))
+		return func(origin string) bool { return strings.ToLower(origin) == exact }, nil
+	}
+}
+
 // CORS 跨域中间件
-func CORS() gin.HandlerFunc {
+// 只对允许的来源回显 Origin（始终附带 Vary: Origin），只应答允许的来源、方法和请求头的预检请求；
+// 不允许的预检请求返回 403，不允许来源的普通请求照常处理但不带 CORS 响应头，由浏览器拦截。
+// 来源配置无效时 panic，使服务在启动时即失败
+func CORS(cfg *config.CORSConfig) gin.HandlerFunc {
+	matchers := make([]originMatcher, 0, len(cfg.AllowOrigins))
+	anyOrigin := false
+	for _, pattern := range cfg.AllowOrigins {
+		matcher, err := newOriginMatcher(pattern)
+		if err != nil {
+			panic(fmt.Sprintf("CORS 来源配置无效 %q: %v", pattern, err))
+		}
+		matchers = append(matchers, matcher)
+		anyOrigin = anyOrigin || pattern == "*"
+	}
+	if anyOrigin && cfg.AllowCredentials {
+		slog.Warn("CORS 允许任意来源并携带凭证，任何网站都能以用户身份调用接口")
+	}
+
+	methods := make([]string, 0, len(cfg.AllowMethods))
+	for _, method := range cfg.AllowMethods {
+		methods = append(methods, strings.ToUpper(method))
+	}
+	anyHeader := slices.Contains(cfg.AllowHeaders, "*")
+	headers := make([]string, 0, len(cfg.AllowHeaders))
+	for _, header := range cfg.AllowHeaders {
+		headers = append(headers, http.CanonicalHeaderKey(header))
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(cfg.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposeHeaders, ", ")
+	maxAge := ""
+	if cfg.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+
+	allowed := func(origin string) bool {
+		for _, match := range matchers {
+			if match(origin) {
+				return true
+			}
+		}
+		return false
+	}
+
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if origin == "" {
+			c.Next()
 			return
 		}
+		if !allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			if !slices.Contains(methods, strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))) {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			requested := c.GetHeader("Access-Control-Request-Headers")
+			if anyHeader {
+				header.Set("Access-Control-Allow-Headers", requested)
+			} else {
+				for _, name := range strings.Split(requested, ",") {
+					name = strings.TrimSpace(name)
+					if name != "" && !slices.Contains(headers, http.CanonicalHeaderKey(name)) {
+						c.AbortWithStatus(http.StatusForbidden)
+						return
+					}
+				}
+				header.Set("Access-Control-Allow-Headers", allowHeaders)
+			}
+			header.Set("Access-Control-Allow-Methods", allowMethods)
+			if maxAge != "" {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+		}
 
-		c.Next()
+		// 携带凭证时浏览器不接受 *，需回显具体来源
+		if anyOrigin && !cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+		c.AbortWithStatus(http.StatusNoContent)
 	}
 }
-
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/rand"
 	"net/http"
 	"strings"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
 )
 
 // Claims JWT声明
@@ -15,18 +17,29 @@ type Claims struct {
 	UserID   uint     `json:"user_id"`
 	Username string   `json:"username"`
 	Roles    []string `json:"roles"`
+	Tenant   string   `json:"tenant,omitempty"` // 登录时选择的租户，可被 X-Tenant 请求头覆盖
 	jwt.RegisteredClaims
 }
 
 var jwtSecret []byte
 
-// InitJWT 初始化JWT密钥
-func InitJWT(cfg *config.JWTConfig) {
+var sessionService = &service.SessionService{}
+
+// InitJWT 初始化JWT密钥和认证方式
+func InitJWT(cfg *config.JWTConfig) error {
+	if err := validateAuthMode(cfg); err != nil {
+		return err
+	}
 	jwtSecret = []byte(cfg.Secret)
+	authCfg = cfg
+
+	// 签发时间精确到毫秒，否则同一秒内吊销后重新登录得到的 Token 会被误判为已吊销
+	jwt.TimePrecision = time.Millisecond
+	return nil
 }
 
 // GenerateToken 生成JWT Token
-func GenerateToken(userID uint, username string, roles []string, cfg *config.JWTConfig) (string, error) {
+func GenerateToken(userID uint, username string, roles []string, tenant string, cfg *config.JWTConfig) (string, error) {
 	nowTime := time.Now()
 	expireTime := nowTime.Add(cfg.ExpireTime)
 
@@ -34,10 +47,12 @@ func GenerateToken(userID uint, username string, roles []string, cfg *config.JWT
 		UserID:   userID,
 		Username: username,
 		Roles:    roles,
+		Tenant:   tenant,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expireTime),
 			IssuedAt:  jwt.NewNumericDate(nowTime),
 			Issuer:    cfg.Issuer,
+			ID:        rand.Text(), // 退出登录时按 ID 吊销单个 Token
 		},
 	}
 
@@ -62,33 +77,66 @@ func ParseToken(tokenString string) (*Claims, error) {
 	return nil, jwt.ErrSignatureInvalid
 }
 
+// RequestClaims 解析请求携带的 Token（Authorization 请求头优先，其次是认证 Cookie）
+// 没有 Token 或 Token 无效、已过期时返回 nil
+func RequestClaims(c *gin.Context) *Claims {
+	tokenString := ""
+	if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+		tokenString, _ = strings.CutPrefix(authHeader, "Bearer ")
+	} else if authCfg.Mode != AuthModeHeader {
+		tokenString, _ = c.Cookie(authCfg.CookieName)
+	}
+	if tokenString == "" {
+		return nil
+	}
+
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return nil
+	}
+	return claims
+}
+
 // JWTAuth JWT认证中间件
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 从请求头获取token
+		// 优先使用 Authorization 请求头，其次是认证 Cookie
+		tokenString, fromCookie := "", false
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "请求头缺少 Authorization",
-			})
-			c.Abort()
-			return
+		if authHeader == "" && authCfg.Mode != AuthModeHeader {
+			if cookie, err := c.Cookie(authCfg.CookieName); err == nil && cookie != "" {
+				tokenString, fromCookie = cookie, true
+			}
 		}
-
-		// 检查Bearer格式
-		parts := strings.SplitN(authHeader, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code":    401,
-				"message": "Authorization 格式错误，需要 Bearer token",
-			})
-			c.Abort()
-			return
+		if !fromCookie {
+			if authHeader == "" {
+				message := "请求头缺少 Authorization"
+				if authCfg.Mode != AuthModeHeader {
+					message = "缺少 Authorization 请求头或会话 Cookie"
+				}
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"code":    401,
+					"message": message,
+				})
+				c.Abort()
+				return
+			}
+
+			// 检查Bearer格式
+			parts := strings.SplitN(authHeader, " ", 2)
+			if !(len(parts) == 2 && parts[0] == "Bearer") {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"code":    401,
+					"message": "Authorization 格式错误，需要 Bearer token",
+				})
+				c.Abort()
+				return
+			}
+			tokenString = parts[1]
 		}
 
 		// 解析token
-		claims, err := ParseToken(parts[1])
+		claims, err := ParseToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
@@ -99,10 +147,44 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
+		// Cookie 会被浏览器自动携带，改变状态的请求必须带上与会话绑定的 CSRF token
+		if fromCookie && !safeMethod(c.Request.Method) && !verifyCSRF(c, tokenString) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "CSRF token 缺失或无效",
+			})
+			c.Abort()
+			return
+		}
+
+		// 检查Token是否已被吊销（退出登录、用户被禁用、删除等）
+		if claims.IssuedAt != nil {
+			revoked, err := sessionService.IsRevoked(c.Request.Context(), claims.UserID, claims.ID, claims.IssuedAt.Time)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"code":    500,
+					"message": "会话检查失败",
+					"error":   err.Error(),
+				})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"code":    401,
+					"message": "Token 已被吊销，请重新登录",
+				})
+				c.Abort()
+				return
+			}
+		}
+
 		// 将用户信息存入上下文
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("roles", claims.Roles)
+		c.Set("tenant", claims.Tenant)
+		withLogAttrs(c, "user_id", claims.UserID, "username", claims.Username)
 
 		c.Next()
 	}
@@ -5,9 +5,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/logging"
 )
 
 // Logger 日志中间件
+// 通过请求范围的 logger 记录，日志带有请求 ID、路由模板以及认证后追加的用户属性；
+// 5xx 以 Error、4xx 以 Warn、其余以 Info 级别记录
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 开始时间
@@ -16,31 +19,32 @@ func Logger() gin.HandlerFunc {
 		// 处理请求
 		c.Next()
 
-		// 结束时间
-		endTime := time.Now()
-
 		// 执行时间
-		latencyTime := endTime.Sub(startTime)
-
-		// 请求方式
-		reqMethod := c.Request.Method
-
-		// 请求路由
-		reqUri := c.Request.RequestURI
+		latencyTime := time.Since(startTime)
 
 		// 状态码
 		statusCode := c.Writer.Status()
 
-		// 请求IP
-		clientIP := c.ClientIP()
+		level := slog.LevelInfo
+		switch {
+		case statusCode >= 500:
+			level = slog.LevelError
+		case statusCode >= 400:
+			level = slog.LevelWarn
+		}
 
-		slog.Info("请求日志",
+		ctx := c.Request.Context()
+		attrs := []any{
 			"status", statusCode,
 			"latency", latencyTime,
-			"ip", clientIP,
-			"method", reqMethod,
-			"uri", reqUri,
-		)
+			"ip", c.ClientIP(),
+			"method", c.Request.Method,
+			"uri", c.Request.RequestURI,
+			"size", max(c.Writer.Size(), 0),
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate); len(errs) > 0 {
+			attrs = append(attrs, "error", errs.String())
+		}
+		logging.FromContext(ctx).Log(ctx, level, "请求日志", attrs...)
 	}
 }
-
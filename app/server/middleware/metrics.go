@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/metrics"
+)
+
+// Metrics HTTP 指标中间件，按方法、路由模板和状态码统计请求数和耗时
+// 按路由模板而不是实际路径统计，避免 /api/users/:id 之类的路由产生无限多的标签值；未匹配的路由统一记为 unmatched
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startTime := time.Now()
+		metrics.HTTPInFlight.Inc()
+		defer metrics.HTTPInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(startTime).Seconds())
+	}
+}
+
+// MetricsToken 让携带静态令牌（Authorization: Bearer <token>）的请求直接访问主端口上 path 路由的指标，供 Prometheus 等抓取程序使用
+// 需放在 JWTAuth 之前；其他请求照常经过 JWTAuth 和 CasbinAuth
+func MetricsToken(token, path string, handler http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.FullPath() != path {
+			c.Next()
+			return
+		}
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Next()
+			return
+		}
+
+		handler.ServeHTTP(c.Writer, c.Request)
+		c.Abort()
+	}
+}
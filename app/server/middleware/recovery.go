@@ -1,10 +1,11 @@
 package middleware
 
 import (
-	"log/slog"
 	"net/http"
+	"runtime/debug"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/logging"
 )
 
 // Recovery 异常恢复中间件
@@ -12,10 +13,13 @@ func Recovery() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				slog.Error("服务器发生panic", "error", err)
+				ctx := c.Request.Context()
+				logging.FromContext(ctx).ErrorContext(ctx, "服务器发生panic", "error", err, "stack", string(debug.Stack()))
 				c.JSON(http.StatusInternalServerError, gin.H{
-					"code":    500,
-					"message": "服务器内部错误",
+					"code":       500,
+					"message":    "服务器内部错误",
+					"request_id": c.GetString("request_id"),
+					"trace_id":   c.GetString("trace_id"),
 				})
 				c.Abort()
 			}
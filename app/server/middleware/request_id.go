@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/logging"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/tracing"
+)
+
+// RequestIDHeader 请求 ID 的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// TraceIDHeader 返回当前请求 trace ID 的响应头，便于根据错误响应查找链路
+const TraceIDHeader = "X-Trace-ID"
+
+// maxRequestIDLength 接受的客户端请求 ID 最大长度
+const maxRequestIDLength = 128
+
+// RequestID 请求 ID 中间件
+// 沿用上游（网关、客户端）传入的合法 X-Request-ID，否则生成新的 ID；
+// ID 写入响应头和 request_id 上下文，并以其创建请求范围的 logger 存入请求的 context。
+// 启用链路追踪时需放在 otelgin 之后，同时通过 X-Trace-ID 响应头返回 trace ID
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		if traceID := tracing.TraceID(c.Request.Context()); traceID != "" {
+			c.Set("trace_id", traceID)
+			c.Header(TraceIDHeader, traceID)
+		}
+
+		logger := slog.Default().With("request_id", id)
+		if route := c.FullPath(); route != "" {
+			logger = logger.With("route", route)
+		}
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), logger))
+		c.Next()
+	}
+}
+
+// validRequestID 只接受长度有限、由可见 ASCII 字符组成的 ID，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID 生成 32 位十六进制的随机 ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// withLogAttrs 在请求范围的 logger 上追加属性，后续中间件、处理器和服务都会带上这些属性
+func withLogAttrs(c *gin.Context, args ...any) {
+	c.Request = c.Request.WithContext(logging.With(c.Request.Context(), args...))
+}
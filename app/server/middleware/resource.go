@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"gorm.io/gorm"
+)
+
+// ResourceLoader 加载请求所访问资源的属性，供条件策略（p2）求值
+// 资源不存在时应返回 gorm.ErrRecordNotFound，CasbinAuth 会在普通策略放行时据此返回 404，否则返回 403
+type ResourceLoader func(c *gin.Context) (*rbac.Resource, error)
+
+var (
+	resourceLoadersMu sync.RWMutex
+	resourceLoaders   = make(map[string]ResourceLoader)
+)
+
+// RegisterResourceLoader 为路由注册资源加载器，path 为路由模板（如 /api/users/:id）
+// 未注册加载器的路由只按普通策略判定
+func RegisterResourceLoader(method, path string, loader ResourceLoader) {
+	resourceLoadersMu.Lock()
+	defer resourceLoadersMu.Unlock()
+	resourceLoaders[method+" "+path] = loader
+}
+
+// resourceLoader 返回当前路由的资源加载器
+func resourceLoader(c *gin.Context) ResourceLoader {
+	resourceLoadersMu.RLock()
+	defer resourceLoadersMu.RUnlock()
+	return resourceLoaders[c.Request.Method+" "+c.FullPath()]
+}
+
+// LoadResource 按路由模板查找 method + path 对应的资源加载器并加载资源属性，供权限解释等代为判定的场景使用
+// 没有匹配的加载器或资源不存在时返回 nil，与 CasbinAuth 一样只按普通策略判定
+func LoadResource(c *gin.Context, method, path string) (*rbac.Resource, error) {
+	var loader ResourceLoader
+	var params gin.Params
+	resourceLoadersMu.RLock()
+	for key, candidate := range resourceLoaders {
+		keyMethod, template, _ := strings.Cut(key, " ")
+		if keyMethod != method {
+			continue
+		}
+		if matched, ok := matchRoute(template, path); ok {
+			loader, params = candidate, matched
+			break
+		}
+	}
+	resourceLoadersMu.RUnlock()
+	if loader == nil {
+		return nil, nil
+	}
+
+	cp := c.Copy()
+	cp.Params = params
+	res, err := loader(cp)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return res, err
+}
+
+// matchRoute 按 gin 路由模板（:name 形式的参数）匹配 path，返回解析出的路径参数
+func matchRoute(template, path string) (gin.Params, bool) {
+	segments := strings.Split(strings.Trim(template, "/"), "/")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) != len(parts) {
+		return nil, false
+	}
+
+	var params gin.Params
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			if parts[i] == "" {
+				return nil, false
+			}
+			params = append(params, gin.Param{Key: name, Value: parts[i]})
+			continue
+		}
+		if segment != parts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
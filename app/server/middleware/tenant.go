@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+	"gorm.io/gorm"
+)
+
+// TenantHeader 指定当前租户的请求头
+const TenantHeader = "X-Tenant"
+
+var tenantService = &service.TenantService{}
+
+// Tenant 租户解析中间件，需放在 JWTAuth 之后
+// 当前租户优先取 X-Tenant 请求头，其次取 Token 中的租户；未指定时使用全局域。
+// 解析成功后会将用户在该租户中的角色合并进 roles，并把租户写入请求上下文以便 GORM 自动过滤租户数据
+func Tenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		code := c.GetHeader(TenantHeader)
+		if code == "" {
+			code = c.GetString("tenant")
+		}
+		userID := c.GetUint("user_id")
+		username := c.GetString("username")
+		roles := c.GetStringSlice("roles")
+
+		if code == "" || code == rbac.GlobalDomain {
+			if !setActiveRoles(c, username, rbac.GlobalDomain, roles) {
+				return
+			}
+			c.Set("tenant", rbac.GlobalDomain)
+			c.Next()
+			return
+		}
+
+		tenant, err := tenantService.ResolveTenant(code, userID, roles)
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{
+					"code":    404,
+					"message": "租户不存在",
+					"tenant":  code,
+				})
+			case errors.Is(err, service.ErrTenantDisabled), errors.Is(err, service.ErrNotTenantMember):
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":    403,
+					"message": err.Error(),
+					"tenant":  code,
+				})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"code":    500,
+					"message": "租户解析失败",
+					"error":   err.Error(),
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		// 合并 Token 中的全局角色与用户在该租户中的有效角色（含经由用户组继承的角色）
+		tenantRoles, err := rbac.GetImplicitRolesForUser(username, tenant.Code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "获取租户角色失败",
+				"error":   err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		merged := append([]string{}, roles...)
+		for _, role := range tenantRoles {
+			if !slices.Contains(merged, role) {
+				merged = append(merged, role)
+			}
+		}
+
+		if !setActiveRoles(c, username, tenant.Code, merged) {
+			return
+		}
+		c.Set("tenant", tenant.Code)
+		c.Set("tenant_id", tenant.ID)
+		c.Request = c.Request.WithContext(database.WithTenant(c.Request.Context(), tenant.ID))
+		withLogAttrs(c, "tenant", tenant.Code)
+
+		c.Next()
+	}
+}
+
+// setActiveRoles 去掉不在有效期内的限时角色后写回 roles，失败时中止请求并返回 false
+// Token 中的角色在登录时确定，临时角色到期后需要在这里立即失效
+func setActiveRoles(c *gin.Context, username, domain string, roles []string) bool {
+	active, err := rbac.ActiveRoles(username, domain, roles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取用户角色失败",
+			"error":   err.Error(),
+		})
+		c.Abort()
+		return false
+	}
+	c.Set("roles", active)
+	return true
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// AuditLog 审计日志
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	Actor  string `gorm:"size:50;index" json:"actor"` // 操作者，系统任务为 system
+	Action string `gorm:"size:50;index" json:"action"`
+	Target string `gorm:"size:255" json:"target"`
+	Detail string `gorm:"type:text" json:"detail,omitempty"`
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Group 用户组模型
+// 授予用户组的角色会通过 Casbin 的 g 规则链被组成员继承；子组继承父组的全部角色
+type Group struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	TenantID    uint   `gorm:"uniqueIndex:idx_groups_tenant_name,where:deleted_at IS NULL;not null;default:0" json:"tenant_id"` // 0 表示全局用户组
+	Name        string `gorm:"uniqueIndex:idx_groups_tenant_name,where:deleted_at IS NULL;size:50;not null" json:"name"`
+	DisplayName string `gorm:"size:100" json:"display_name"`
+	Description string `gorm:"size:255" json:"description"`
+	ParentID    *uint  `gorm:"index" json:"parent_id"`
+
+	// 关联
+	Parent  *Group `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
+	Members []User `gorm:"many2many:group_members;" json:"members,omitempty"`
+}
+
+// TableName 指定表名
+func (Group) TableName() string {
+	return "groups"
+}
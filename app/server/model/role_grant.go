@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// RoleGrant 限时角色授权（临时提权）
+// 审批通过后写入 Casbin g 规则（user, role, dom），NotBefore/ExpiresAt 在权限判定时生效，
+// 到期后由后台清理任务删除 g 规则并记录审计日志
+type RoleGrant struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Username  string     `gorm:"size:50;not null;index" json:"username"`
+	Role      string     `gorm:"size:50;not null" json:"role"`
+	Domain    string     `gorm:"size:50;not null;default:'*'" json:"dom"`
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	Reason    string     `gorm:"size:255" json:"reason"`
+	Status    string     `gorm:"size:20;not null;index" json:"status"`
+
+	RequestedBy string     `gorm:"size:50" json:"requested_by"`
+	ReviewedBy  string     `gorm:"size:50" json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// 限时授权状态
+const (
+	RoleGrantPending  = "pending"  // 待审批
+	RoleGrantApproved = "approved" // 已批准（在有效期内生效）
+	RoleGrantRejected = "rejected" // 已拒绝
+	RoleGrantRevoked  = "revoked"  // 已提前撤销
+	RoleGrantExpired  = "expired"  // 已到期并被清理
+)
+
+// TableName 指定表名
+func (RoleGrant) TableName() string {
+	return "role_grants"
+}
+
+// Active 授权在指定时间是否处于有效期内
+func (g *RoleGrant) Active(now time.Time) bool {
+	if g.Status != RoleGrantApproved {
+		return false
+	}
+	if g.NotBefore != nil && now.Before(*g.NotBefore) {
+		return false
+	}
+	return now.Before(g.ExpiresAt)
+}
+
+// UserRole 用户与角色的关联（user_roles 表），可带有效期
+type UserRole struct {
+	UserID    uint       `gorm:"primaryKey"`
+	RoleID    uint       `gorm:"primaryKey"`
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at,omitempty"`
+}
+
+// TableName 指定表名
+func (UserRole) TableName() string {
+	return "user_roles"
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tenant 租户（组织）模型
+// Code 同时作为 Casbin 中的域（domain），用户在不同租户中可拥有不同角色
+type Tenant struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Code        string `gorm:"uniqueIndex:idx_tenants_code_active,where:deleted_at IS NULL;size:50;not null" json:"code"`
+	Name        string `gorm:"size:100;not null" json:"name"`
+	Description string `gorm:"size:255" json:"description"`
+	Status      int    `gorm:"default:1" json:"status"` // 1:启用 0:禁用
+}
+
+// TableName 指定表名
+func (Tenant) TableName() string {
+	return "tenants"
+}
+
+// TenantMember 租户成员
+// 含 TenantID 字段的模型属于租户数据，查询时会按当前租户自动过滤
+type TenantMember struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	TenantID uint `gorm:"uniqueIndex:idx_tenant_members_tenant_user;not null" json:"tenant_id"`
+	UserID   uint `gorm:"uniqueIndex:idx_tenant_members_tenant_user;index;not null" json:"user_id"`
+
+	// 关联
+	Tenant *Tenant `gorm:"foreignKey:TenantID" json:"tenant,omitempty"`
+	User   *User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
+
+	// 成员在该租户中的角色，来自 Casbin，不落库
+	Roles []string `gorm:"-" json:"roles"`
+}
+
+// TableName 指定表名
+func (TenantMember) TableName() string {
+	return "tenant_members"
+}
@@ -13,17 +13,24 @@ type User struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 	
-	Username string `gorm:"uniqueIndex;size:50;not null" json:"username"`
-	Email    string `gorm:"uniqueIndex;size:100;not null" json:"email"`
+	Username string `gorm:"uniqueIndex:idx_users_username_active,where:deleted_at IS NULL;size:50;not null" json:"username"`
+	Email    string `gorm:"uniqueIndex:idx_users_email_active,where:deleted_at IS NULL;size:100;not null" json:"email"`
 	Password string `gorm:"size:255;not null" json:"-"`
 	Nickname string `gorm:"size:50" json:"nickname"`
 	Avatar   string `gorm:"size:255" json:"avatar"`
-	Status   int    `gorm:"default:1" json:"status"` // 1:正常 0:禁用
+	Status   int    `gorm:"default:1" json:"status"` // 1:正常 0:禁用 -1:已清除
 	
 	// 关联
 	Roles []Role `gorm:"many2many:user_roles;" json:"roles,omitempty"`
 }
 
+// 用户状态
+const (
+	UserStatusActive   = 1  // 正常
+	UserStatusDisabled = 0  // 禁用
+	UserStatusPurged   = -1 // 已清除（个人信息已匿名化）
+)
+
 // TableName 指定表名
 func (User) TableName() string {
 	return "users"
@@ -39,13 +46,22 @@ type Role struct {
 	Name        string `gorm:"uniqueIndex;size:50;not null" json:"name"`
 	DisplayName string `gorm:"size:100" json:"display_name"`
 	Description string `gorm:"size:255" json:"description"`
-	Status      int    `gorm:"default:1" json:"status"` // 1:启用 0:禁用
+	Status      int    `gorm:"default:1" json:"status"`                      // 1:启用 0:禁用
+	Scope       string `gorm:"size:20;not null;default:global" json:"scope"` // 授予范围，见 RoleScopeGlobal、RoleScopeTenant
 	
 	// 关联
 	Users       []User       `gorm:"many2many:user_roles;" json:"users,omitempty"`
 	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
+	Parents     []Role       `gorm:"many2many:role_parents;joinForeignKey:RoleID;joinReferences:ParentID" json:"parents,omitempty"` // 继承的父角色，同步为 Casbin g 规则（子角色, 父角色, *）
 }
 
+// 角色的授予范围
+// 默认策略都属于全局域，租户内授予的角色同样会获得其全部策略，因此只有明确标记为租户范围的角色才能在租户内授予
+const (
+	RoleScopeGlobal = "global" // 只能在全局域授予
+	RoleScopeTenant = "tenant" // 也可以在租户内授予，包括由租户管理员授予
+)
+
 // TableName 指定表名
 func (Role) TableName() string {
 	return "roles"
@@ -58,7 +74,7 @@ type Permission struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 	
-	Name        string `gorm:"uniqueIndex;size:50;not null" json:"name"`
+	Name        string `gorm:"uniqueIndex:idx_permissions_name_active,where:deleted_at IS NULL;size:50;not null" json:"name"`
 	DisplayName string `gorm:"size:100" json:"display_name"`
 	Description string `gorm:"size:255" json:"description"`
 	Resource    string `gorm:"size:100;not null" json:"resource"` // 资源路径
@@ -0,0 +1,153 @@
+package rbac
+
+import (
+	"expvar"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/casbin/casbin/v2/model"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// decision 缓存的判定结果
+type decision struct {
+	allowed bool
+	rule    []string
+}
+
+// decisionCache 判定缓存，策略变更时整体替换为新的空缓存
+// 替换是原子的：替换前开始的判定即使在替换后才写入，也只会写进已丢弃的旧缓存
+var decisionCache atomic.Pointer[lru.Cache[string, decision]]
+
+// decisionCacheSize 缓存容量，0 表示关闭缓存
+var decisionCacheSize int
+
+var (
+	cacheHits          atomic.Int64
+	cacheMisses        atomic.Int64
+	cacheInvalidations atomic.Int64
+)
+
+func init() {
+	expvar.Publish("casbin_decision_cache", expvar.Func(func() any { return GetCacheStats() }))
+}
+
+// CacheStats 判定缓存的统计
+type CacheStats struct {
+	Enabled       bool  `json:"enabled"`
+	Size          int   `json:"size"`
+	Capacity      int   `json:"capacity"`
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Invalidations int64 `json:"invalidations"`
+}
+
+// GetCacheStats 返回判定缓存的命中统计
+func GetCacheStats() CacheStats {
+	stats := CacheStats{
+		Capacity:      decisionCacheSize,
+		Hits:          cacheHits.Load(),
+		Misses:        cacheMisses.Load(),
+		Invalidations: cacheInvalidations.Load(),
+	}
+	if cache := decisionCache.Load(); cache != nil {
+		stats.Enabled = true
+		stats.Size = cache.Len()
+	}
+	return stats
+}
+
+// initDecisionCache 按容量创建判定缓存，size 为 0 时关闭
+func initDecisionCache(size int) error {
+	decisionCacheSize = size
+	if size <= 0 {
+		decisionCache.Store(nil)
+		return nil
+	}
+	cache, err := lru.New[string, decision](size)
+	if err != nil {
+		return err
+	}
+	decisionCache.Store(cache)
+	return nil
+}
+
+// policyGeneration 策略代数，每次策略变更加一，用于判断按策略统计的缓存（如 hasConditionalPolicies）是否过期
+var policyGeneration atomic.Int64
+
+// invalidateDecisions 策略变更后丢弃全部缓存的判定
+// 必须在内存中的策略更新之后调用
+func invalidateDecisions() {
+	policyGeneration.Add(1)
+	if decisionCache.Load() == nil {
+		return
+	}
+	cache, err := lru.New[string, decision](decisionCacheSize)
+	if err != nil {
+		return
+	}
+	decisionCache.Store(cache)
+	cacheInvalidations.Add(1)
+}
+
+// cachedDecide 带缓存的 decide，缓存键为（去重排序后的角色, 域, 资源, 操作）
+func cachedDecide(subjects []string, domain, resource, action string) (bool, []string, error) {
+	cache := decisionCache.Load()
+	if cache == nil {
+		return decide(subjects, domain, resource, action)
+	}
+
+	key := decisionKey(subjects, domain, resource, action)
+	if d, ok := cache.Get(key); ok {
+		cacheHits.Add(1)
+		return d.allowed, d.rule, nil
+	}
+	cacheMisses.Add(1)
+
+	allowed, rule, err := decide(subjects, domain, resource, action)
+	if err != nil {
+		return false, nil, err
+	}
+	cache.Add(key, decision{allowed: allowed, rule: rule})
+	return allowed, rule, nil
+}
+
+// decisionKey 生成缓存键，角色顺序和重复不影响判定结果
+func decisionKey(subjects []string, domain, resource, action string) string {
+	roles := slices.Clone(subjects)
+	slices.Sort(roles)
+	roles = slices.Compact(roles)
+	return strings.Join(roles, ",") + "\x00" + domain + "\x00" + resource + "\x00" + action
+}
+
+// cacheWatcher 未启用 Redis 同步时使用的本地 watcher，只负责在本节点策略变更后清空判定缓存
+type cacheWatcher struct{}
+
+func (cacheWatcher) SetUpdateCallback(func(string)) error { return nil }
+func (cacheWatcher) Update() error                        { invalidateDecisions(); return nil }
+func (cacheWatcher) Close()                               {}
+func (cacheWatcher) UpdateForAddPolicy(string, string, ...string) error {
+	invalidateDecisions()
+	return nil
+}
+func (cacheWatcher) UpdateForRemovePolicy(string, string, ...string) error {
+	invalidateDecisions()
+	return nil
+}
+func (cacheWatcher) UpdateForRemoveFilteredPolicy(string, string, int, ...string) error {
+	invalidateDecisions()
+	return nil
+}
+func (cacheWatcher) UpdateForSavePolicy(model.Model) error {
+	invalidateDecisions()
+	return nil
+}
+func (cacheWatcher) UpdateForAddPolicies(string, string, ...[]string) error {
+	invalidateDecisions()
+	return nil
+}
+func (cacheWatcher) UpdateForRemovePolicies(string, string, ...[]string) error {
+	invalidateDecisions()
+	return nil
+}
@@ -0,0 +1,108 @@
+package rbac
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 策略效果组合方式
+const (
+	// EffectAllow 仅允许规则生效：任一规则允许即放行，deny 规则被忽略
+	EffectAllow = "allow"
+	// EffectDenyOverride 拒绝优先：任一 deny 规则命中即拒绝，否则任一 allow 规则命中即放行
+	EffectDenyOverride = "deny-override"
+	// EffectPriority 优先级：按 priority 从小到大取第一条命中的规则，由其 eft 决定
+	EffectPriority = "priority"
+)
+
+// 规则的 eft 取值
+const (
+	EftAllow = "allow"
+	EftDeny  = "deny"
+)
+
+// DefaultPriority 未指定优先级时规则使用的优先级（数值越小优先级越高）
+const DefaultPriority = 100
+
+// effectExpressions 各组合方式对应的 Casbin policy_effect 表达式
+var effectExpressions = map[string]string{
+	EffectAllow:        "some(where (p.eft == allow))",
+	EffectDenyOverride: "some(where (p.eft == allow)) && !some(where (p.eft == deny))",
+	EffectPriority:     "priority(p.eft) || deny",
+}
+
+// effect 当前使用的效果组合方式
+var effect = EffectDenyOverride
+
+// Effect 返回当前使用的效果组合方式
+func Effect() string {
+	return effect
+}
+
+// effectExpression 返回组合方式对应的表达式
+func effectExpression(mode string) (string, error) {
+	expr, ok := effectExpressions[mode]
+	if !ok {
+		return "", fmt.Errorf("不支持的策略效果: %s（可选 %s、%s、%s）", mode, EffectAllow, EffectDenyOverride, EffectPriority)
+	}
+	return expr, nil
+}
+
+// ValidEft 检查规则的 eft 是否合法
+func ValidEft(eft string) bool {
+	return eft == EftAllow || eft == EftDeny
+}
+
+// rulePriority 返回规则的优先级，缺省或无法解析时为 DefaultPriority
+func rulePriority(rule []string) int {
+	if len(rule) > 5 {
+		if priority, err := strconv.Atoi(rule[5]); err == nil {
+			return priority
+		}
+	}
+	return DefaultPriority
+}
+
+// decide 对一组主体（通常是用户的全部角色）做出与当前效果组合方式一致的判定
+// 返回判定结果以及决定结果的规则；没有规则命中时规则为 nil
+//
+// Casbin 只能对单个主体求值，这里逐个角色调用 EnforceEx 再按组合方式合并：
+// 拒绝优先时任一角色命中 deny 规则即拒绝；优先级模式下取所有角色命中规则中优先级最高的一条，同级时 deny 优先。
+func decide(subjects []string, domain, resource, action string) (bool, []string, error) {
+	allowed := false
+	var decisive []string
+
+	for _, subject := range subjects {
+		ok, rule, err := Enforcer.EnforceEx(subject, domain, resource, action)
+		if err != nil {
+			return false, nil, err
+		}
+		if len(rule) == 0 {
+			continue
+		}
+
+		switch effect {
+		case EffectPriority:
+			if decisive == nil || outranks(rule, ok, decisive, allowed) {
+				allowed, decisive = ok, rule
+			}
+		default:
+			if !ok {
+				return false, rule, nil
+			}
+			if decisive == nil {
+				allowed, decisive = true, rule
+			}
+		}
+	}
+	return allowed, decisive, nil
+}
+
+// outranks 判断规则 a 是否比规则 b 优先：优先级数值更小，或同级时 a 为拒绝而 b 为允许
+func outranks(a []string, aAllowed bool, b []string, bAllowed bool) bool {
+	pa, pb := rulePriority(a), rulePriority(b)
+	if pa != pb {
+		return pa < pb
+	}
+	return !aAllowed && bAllowed
+}
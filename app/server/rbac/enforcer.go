@@ -3,14 +3,25 @@ package rbac
 import (
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/util"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	appmodel "github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
 )
 
-var Enforcer *casbin.Enforcer
+// Enforcer 全局 Casbin 执行器
+// 使用 SyncedEnforcer：策略同步器会在后台 goroutine 中修改策略，需要与请求鉴权并发安全
+var Enforcer *casbin.SyncedEnforcer
+
+// GlobalDomain 全局域：该域下的角色和策略对所有租户生效，未指定租户时也使用该域
+const GlobalDomain = "*"
 
 // InitCasbin 初始化Casbin
 func InitCasbin(cfg *config.CasbinConfig) error {
@@ -20,120 +31,293 @@ func InitCasbin(cfg *config.CasbinConfig) error {
 		return fmt.Errorf("failed to create casbin adapter: %w", err)
 	}
 
+	// 将旧版无域规则迁移到全局域
+	if err := migrateLegacyRules(); err != nil {
+		return fmt.Errorf("failed to migrate casbin rules: %w", err)
+	}
+
+	// 加载模型，并按配置替换策略效果（policy_effect）
+	expr, err := effectExpression(cfg.Effect)
+	if err != nil {
+		return err
+	}
+	m, err := model.NewModelFromFile(cfg.ModelPath)
+	if err != nil {
+		return fmt.Errorf("failed to load casbin model: %w", err)
+	}
+	m.AddDef("e", "e", expr)
+	m.AddDef("e", "e2", conditionalEffect(cfg.Effect))
+	effect = cfg.Effect
+
 	// 创建enforcer
-	Enforcer, err = casbin.NewEnforcer(cfg.ModelPath, adapter)
+	Enforcer, err = casbin.NewSyncedEnforcer(m, adapter)
 	if err != nil {
 		return fmt.Errorf("failed to create casbin enforcer: %w", err)
 	}
 
+	// 每条规则变更通过适配器单独写库，不再整体重写 casbin_rule 表
+	Enforcer.EnableAutoSave(true)
+
+	// 让全局域（*）中的角色分配在任意租户域中生效
+	Enforcer.AddNamedDomainMatchingFunc("g", "keyMatch", util.KeyMatch)
+
+	// 判定缓存，策略变更时由 watcher 清空
+	if err := initDecisionCache(cfg.DecisionCacheSize); err != nil {
+		return fmt.Errorf("failed to create decision cache: %w", err)
+	}
+
 	// 从数据库加载策略
 	if err := Enforcer.LoadPolicy(); err != nil {
 		return fmt.Errorf("failed to load policy: %w", err)
 	}
+	invalidateDecisions()
+	lastReload.Store(time.Now().Unix())
 
-	slog.Info("Casbin 初始化成功")
+	// 多副本部署时通过 Redis 同步策略变更，否则只在本节点策略变更时清空判定缓存
+	if cfg.WatcherEnabled && database.RDB != nil {
+		if err := startWatcher(cfg); err != nil {
+			return fmt.Errorf("failed to start casbin watcher: %w", err)
+		}
+	} else if err := Enforcer.SetWatcher(cacheWatcher{}); err != nil {
+		return fmt.Errorf("failed to set casbin watcher: %w", err)
+	}
+
+	slog.Info("Casbin 初始化成功", "effect", effect, "policy_version", policyVersion.Load(), "decision_cache", cfg.DecisionCacheSize)
 	return nil
 }
 
+// defaultPolicies 默认权限策略（sub, obj, act），均属于全局域
+var defaultPolicies = [][]string{
+	// 管理员权限
+	{"admin", "/api/users", "GET"},
+	{"admin", "/api/users", "POST"},
+	{"admin", "/api/users", "PUT"},
+	{"admin", "/api/users", "DELETE"},
+	{"admin", "/api/users/:id", "GET"},
+	{"admin", "/api/users/:id", "PUT"},
+	{"admin", "/api/users/:id", "DELETE"},
+	{"admin", "/api/users/:id/disable", "POST"},
+	{"admin", "/api/users/:id/enable", "POST"},
+	{"admin", "/api/users/:id/restore", "POST"},
+	{"admin", "/api/users/:id/purge", "DELETE"},
+	{"admin", "/api/users/deleted", "GET"},
+	{"admin", "/api/users/export", "GET"},
+	{"admin", "/api/users/import", "POST"},
+	{"admin", "/api/roles", "GET"},
+	{"admin", "/api/roles", "POST"},
+	{"admin", "/api/roles", "PUT"},
+	{"admin", "/api/roles", "DELETE"},
+	{"admin", "/api/roles/:id/permissions/resolved", "GET"},
+	{"admin", "/api/roles/:id/parents", "GET"},
+	{"admin", "/api/roles/:id/parents", "POST"},
+	{"admin", "/api/roles/:id/parents/:parent_id", "DELETE"},
+	{"admin", "/api/permissions", "GET"},
+	{"admin", "/api/permissions", "POST"},
+	{"admin", "/api/permissions", "PUT"},
+	{"admin", "/api/permissions", "DELETE"},
+	{"admin", "/api/users/:id/effective-roles", "GET"},
+	{"admin", "/api/authz/explain", "GET"},
+	{"admin", "/api/authz/policy-status", "GET"},
+	{"admin", "/api/policies", "GET"},
+	{"admin", "/api/policies", "POST"},
+	{"admin", "/api/policies", "PUT"},
+	{"admin", "/api/policies", "DELETE"},
+	{"admin", "/api/policies/roles/:role/members", "GET"},
+	{"admin", "/api/policies/roles/:role/members", "POST"},
+	{"admin", "/api/policies/roles/:role/members/:username", "DELETE"},
+	{"admin", "/api/role-grants", "GET"},
+	{"admin", "/api/role-grants/:id/approve", "POST"},
+	{"admin", "/api/role-grants/:id/reject", "POST"},
+	{"admin", "/api/role-grants/:id/revoke", "POST"},
+	{"admin", "/api/groups", "GET"},
+	{"admin", "/api/groups", "POST"},
+	{"admin", "/api/groups/:id", "GET"},
+	{"admin", "/api/groups/:id", "PUT"},
+	{"admin", "/api/groups/:id", "DELETE"},
+	{"admin", "/api/groups/:id/parent", "PUT"},
+	{"admin", "/api/groups/:id/members", "GET"},
+	{"admin", "/api/groups/:id/members", "POST"},
+	{"admin", "/api/groups/:id/members/:user_id", "DELETE"},
+	{"admin", "/api/groups/:id/roles", "GET"},
+	{"admin", "/api/groups/:id/roles", "POST"},
+	{"admin", "/api/groups/:id/roles/:role", "DELETE"},
+	{"admin", "/api/tenants", "GET"},
+	{"admin", "/api/tenants", "POST"},
+	{"admin", "/api/tenants/:id", "GET"},
+	{"admin", "/api/tenants/:id", "PUT"},
+	{"admin", "/api/tenants/:id", "DELETE"},
+	{"admin", "/api/tenants/:id/members", "GET"},
+	{"admin", "/api/tenants/:id/members", "POST"},
+	{"admin", "/api/tenants/:id/members/:user_id", "DELETE"},
+	{"admin", "/api/tenant/members", "GET"},
+	{"admin", "/api/tenant/members", "POST"},
+	{"admin", "/api/tenant/members/:user_id", "DELETE"},
+	{"admin", "/api/dashboard", "GET"},
+	{"admin", "/api/debug/pprof/", "GET"},
+	{"admin", "/api/debug/pprof/:name", "GET"},
+	{"admin", "/api/debug/pprof/:name", "POST"},
+	{"admin", "/api/debug/runtime", "GET"},
+	{"admin", "/api/debug/config", "GET"},
+	{"admin", "/api/debug/loglevel", "GET"},
+	{"admin", "/api/debug/loglevel", "PUT"},
+	{"admin", "/api/metrics", "GET"},
+
+	// 普通用户权限
+	{"user", "/api/users/profile", "GET"},
+	{"user", "/api/users/profile", "PUT"},
+	{"user", "/api/dashboard", "GET"},
+
+	// 访客权限
+	{"guest", "/api/public", "GET"},
+
+	// 租户管理员权限（仅在被授予该角色的租户内生效）
+	{"tenant_admin", "/api/tenant/members", "GET"},
+	{"tenant_admin", "/api/tenant/members", "POST"},
+	{"tenant_admin", "/api/tenant/members/:user_id", "DELETE"},
+}
+
+// defaultConditional 默认条件策略
+var defaultConditional = []Policy{
+	// 普通用户可以查看自己的用户信息
+	{Subject: "user", Object: "/api/users/:id", Action: "GET", Condition: "r2.res.OwnerID == r2.user.ID"},
+}
+
+// DefaultPolicies 返回内置的默认策略（p 和 p2），不含角色分配
+// configs/rbac_policy.csv 由它生成（server policy export --defaults），按策略文件同步时也不会删除其中的规则
+func DefaultPolicies() *PolicySet {
+	set := &PolicySet{}
+	for _, policy := range defaultPolicies {
+		set.add(Policy{Subject: policy[0], Object: policy[1], Action: policy[2]})
+	}
+	for _, policy := range defaultConditional {
+		set.add(policy)
+	}
+	return set
+}
+
 // InitDefaultPolicies 初始化默认策略
 func InitDefaultPolicies() error {
-	// 添加默认角色
+	// 添加默认角色，已存在的角色保持不变
 	roles := []struct {
-		name string
-		desc string
+		name  string
+		desc  string
+		scope string
 	}{
-		{"admin", "管理员"},
-		{"user", "普通用户"},
-		{"guest", "访客"},
-	}
-
-	// 添加默认权限策略
-	policies := [][]string{
-		// 管理员权限
-		{"admin", "/api/users", "GET"},
-		{"admin", "/api/users", "POST"},
-		{"admin", "/api/users", "PUT"},
-		{"admin", "/api/users", "DELETE"},
-		{"admin", "/api/users/:id", "GET"},
-		{"admin", "/api/users/:id", "PUT"},
-		{"admin", "/api/users/:id", "DELETE"},
-		{"admin", "/api/roles", "GET"},
-		{"admin", "/api/roles", "POST"},
-		{"admin", "/api/roles", "PUT"},
-		{"admin", "/api/roles", "DELETE"},
-		{"admin", "/api/permissions", "GET"},
-		{"admin", "/api/permissions", "POST"},
-		{"admin", "/api/permissions", "PUT"},
-		{"admin", "/api/permissions", "DELETE"},
-		{"admin", "/api/dashboard", "GET"},
-
-		// 普通用户权限
-		{"user", "/api/users/profile", "GET"},
-		{"user", "/api/users/profile", "PUT"},
-		{"user", "/api/dashboard", "GET"},
-
-		// 访客权限
-		{"guest", "/api/public", "GET"},
-	}
-
-	// 添加策略
-	for _, policy := range policies {
-		_, err := Enforcer.AddPolicy(policy)
-		if err != nil {
-			slog.Warn("添加策略失败", "policy", policy, "error", err)
+		{"admin", "管理员", appmodel.RoleScopeGlobal},
+		{"user", "普通用户", appmodel.RoleScopeTenant},
+		{"guest", "访客", appmodel.RoleScopeTenant},
+		{"tenant_admin", "租户管理员", appmodel.RoleScopeTenant},
+	}
+	for _, role := range roles {
+		record := appmodel.Role{Name: role.name}
+		if err := database.DB.Where(&record).Attrs(appmodel.Role{DisplayName: role.desc, Scope: role.scope}).
+			FirstOrCreate(&record).Error; err != nil {
+			return fmt.Errorf("failed to add default role %s: %w", role.name, err)
 		}
 	}
 
-	// 保存策略
-	if err := Enforcer.SavePolicy(); err != nil {
-		return fmt.Errorf("failed to save policy: %w", err)
+	// 添加默认策略（均属于全局域），已存在的规则会被跳过，可重复执行
+	defaults := DefaultPolicies()
+	added, err := AddPolicies(defaults.Policies)
+	if err != nil {
+		return fmt.Errorf("failed to add default policies: %w", err)
 	}
+	n, err := AddConditionalPolicies(defaults.Conditional)
+	if err != nil {
+		return fmt.Errorf("failed to add default conditional policies: %w", err)
+	}
+	added += n
 
-	slog.Info("默认策略初始化完成", "roles", len(roles), "policies", len(policies))
+	slog.Info("默认策略初始化完成", "roles", len(roles), "policies", len(defaults.Policies)+len(defaults.Conditional), "added", added)
 	return nil
 }
 
-// CheckPermission 检查权限
-func CheckPermission(role, resource, action string) (bool, error) {
-	return Enforcer.Enforce(role, resource, action)
+// CheckPermission 检查角色在指定域中的权限
+func CheckPermission(role, domain, resource, action string) (bool, error) {
+	return Enforcer.Enforce(role, domain, resource, action)
 }
 
-// AddRoleForUser 为用户添加角色
+// AddRoleForUser 为用户添加全局角色
 func AddRoleForUser(username, role string) (bool, error) {
-	return Enforcer.AddRoleForUser(username, role)
+	return Enforcer.AddRoleForUser(username, role, GlobalDomain)
 }
 
-// DeleteRoleForUser 删除用户角色
+// AddRoleForUserInDomain 为用户添加指定租户域中的角色
+func AddRoleForUserInDomain(username, role, domain string) (bool, error) {
+	return Enforcer.AddRoleForUserInDomain(username, role, domain)
+}
+
+// DeleteRoleForUser 删除用户的全局角色
 func DeleteRoleForUser(username, role string) (bool, error) {
-	return Enforcer.DeleteRoleForUser(username, role)
+	return Enforcer.DeleteRoleForUser(username, role, GlobalDomain)
+}
+
+// DeleteRoleForUserInDomain 删除用户在指定租户域中的某个角色
+func DeleteRoleForUserInDomain(username, role, domain string) (bool, error) {
+	return Enforcer.DeleteRoleForUserInDomain(username, role, domain)
+}
+
+// DeleteRolesForUserInDomain 删除用户在指定租户域中的所有角色
+func DeleteRolesForUserInDomain(username, domain string) (bool, error) {
+	return Enforcer.DeleteRolesForUserInDomain(username, domain)
+}
+
+// DeleteUser 删除用户的所有角色及策略
+func DeleteUser(username string) (bool, error) {
+	return Enforcer.DeleteUser(username)
 }
 
-// GetRolesForUser 获取用户的所有角色
+// DeleteDomain 删除租户域中的所有角色分配和策略
+func DeleteDomain(domain string) (bool, error) {
+	return Enforcer.DeleteDomains(domain)
+}
+
+// GetRolesForUser 获取用户的所有全局角色
 func GetRolesForUser(username string) ([]string, error) {
-	return Enforcer.GetRolesForUser(username)
+	return Enforcer.GetRolesForUser(username, GlobalDomain)
+}
+
+// GetRolesForUserInDomain 获取用户在指定租户域中的角色（包含全局角色）
+func GetRolesForUserInDomain(username, domain string) []string {
+	return Enforcer.GetRolesForUserInDomain(username, domain)
 }
 
-// GetUsersForRole 获取角色的所有用户
+// GetDomainRolesForUser 获取用户直接在指定租户域中被授予的角色（不含全局角色）
+func GetDomainRolesForUser(username, domain string) []string {
+	rules, _ := Enforcer.GetFilteredGroupingPolicy(0, username, "", domain)
+	roles := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		roles = append(roles, rule[1])
+	}
+	return roles
+}
+
+// GetUsersForRole 获取全局角色的所有用户
 func GetUsersForRole(role string) ([]string, error) {
-	return Enforcer.GetUsersForRole(role)
+	return Enforcer.GetUsersForRole(role, GlobalDomain)
 }
 
-// AddPolicy 添加策略
-func AddPolicy(role, resource, action string) (bool, error) {
-	ok, err := Enforcer.AddPolicy(role, resource, action)
+// RoleExists 检查角色是否存在于策略或角色继承规则中
+func RoleExists(role string) (bool, error) {
+	subjects, err := Enforcer.GetAllSubjects()
 	if err != nil {
 		return false, err
 	}
-	return ok, Enforcer.SavePolicy()
-}
-
-// RemovePolicy 删除策略
-func RemovePolicy(role, resource, action string) (bool, error) {
-	ok, err := Enforcer.RemovePolicy(role, resource, action)
+	roles, err := Enforcer.GetAllRoles()
 	if err != nil {
 		return false, err
 	}
-	return ok, Enforcer.SavePolicy()
+	return slices.Contains(subjects, role) || slices.Contains(roles, role), nil
+}
+
+// AddPolicy 添加全局允许策略（默认优先级）
+func AddPolicy(role, resource, action string) (bool, error) {
+	return Enforcer.AddPolicy(Policy{Subject: role, Object: resource, Action: action}.Rule())
+}
+
+// RemovePolicy 删除全局允许策略（默认优先级）
+func RemovePolicy(role, resource, action string) (bool, error) {
+	return Enforcer.RemovePolicy(Policy{Subject: role, Object: resource, Action: action}.Rule())
 }
 
 // GetPoliciesForRole 获取角色的所有策略
@@ -141,3 +325,22 @@ func GetPoliciesForRole(role string) ([][]string, error) {
 	return Enforcer.GetFilteredPolicy(0, role)
 }
 
+// migrateLegacyRules 将旧规则迁移到当前模型
+// 旧模型为 p = sub, obj, act / g = _, _，之后先在主体后增加了 dom 字段，又在末尾增加了 eft 和 priority 字段
+func migrateLegacyRules() error {
+	statements := []struct {
+		sql  string
+		args []any
+	}{
+		{"UPDATE casbin_rule SET v3 = v2, v2 = v1, v1 = ? WHERE ptype = 'p' AND v2 <> '' AND v3 = ''", []any{GlobalDomain}},
+		{"UPDATE casbin_rule SET v2 = ? WHERE ptype = 'g' AND v1 <> '' AND v2 = ''", []any{GlobalDomain}},
+		{"UPDATE casbin_rule SET v4 = ? WHERE ptype = 'p' AND v4 = ''", []any{EftAllow}},
+		{"UPDATE casbin_rule SET v5 = ? WHERE ptype = 'p' AND v5 = ''", []any{strconv.Itoa(DefaultPriority)}},
+	}
+	for _, stmt := range statements {
+		if err := database.DB.Exec(stmt.sql, stmt.args...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package rbac
+
+// Authorize 检查一组角色在指定域中是否有权访问资源，多个角色的结果按当前效果组合方式合并
+func Authorize(roles []string, domain, resource, action string) (bool, error) {
+	allowed, _, err := cachedDecide(roles, domain, resource, action)
+	return allowed, err
+}
+
+// Explanation 权限判定的解释
+type Explanation struct {
+	Subject     string       `json:"subject"`
+	Domain      string       `json:"domain"`
+	Object      string       `json:"object"`
+	Action      string       `json:"action"`
+	Allowed     bool         `json:"allowed"`
+	Mode        string       `json:"mode"`               // 效果组合方式
+	Ptype       string       `json:"ptype,omitempty"`    // 决定结果的策略类型：p 为普通策略，p2 为条件策略
+	Effect      string       `json:"effect,omitempty"`   // 决定结果的策略的 eft
+	Policy      []string     `json:"policy,omitempty"`   // 决定结果的策略
+	Chain       []string     `json:"chain,omitempty"`    // 从用户到策略主体的继承链
+	ActiveRoles []string     `json:"active_roles"`       // 实际参与判定的角色（与登录后 Token 及租户中间件计算的一致）
+	Roles       []RoleSource `json:"roles"`              // 用户在该域中的全部角色及来源，含不在有效期内的限时授权
+	Resource    *Resource    `json:"resource,omitempty"` // 参与条件策略求值的资源属性，为空时不评估条件策略
+	Reason      string       `json:"reason"`
+}
+
+// Explain 解释用户在指定域中访问资源的判定结果
+// 与 CasbinAuth 走同一条判定路径：按 ResolveRoles 计算角色，再由 AuthorizeRequest 的逻辑合并普通策略与条件策略，
+// 返回决定结果的策略（允许或拒绝）以及用户是经由哪条角色链获得该策略的。req.Roles 为空时按 req.User.Username 计算
+func Explain(req *Request) (*Explanation, error) {
+	username := req.User.Username
+	if req.Roles == nil {
+		roles, err := ResolveRoles(username, req.Domain)
+		if err != nil {
+			return nil, err
+		}
+		req.Roles = roles
+	}
+
+	allowed, policy, ptype, err := authorize(req)
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := ExplainRoles(username, req.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Explanation{
+		Subject:     username,
+		Domain:      req.Domain,
+		Object:      req.Object,
+		Action:      req.Action,
+		Allowed:     allowed,
+		Mode:        effect,
+		ActiveRoles: req.Roles,
+		Roles:       sources,
+		Resource:    req.Resource,
+	}
+	if sources == nil {
+		result.Roles = []RoleSource{}
+	}
+
+	if len(policy) == 0 {
+		result.Reason = "没有匹配的策略"
+		return result, nil
+	}
+
+	result.Ptype = ptype
+	result.Policy = policy
+	result.Chain = roleChain(username, policy[0], sources)
+	eft := 4
+	kind := ""
+	if ptype == ConditionalPtype {
+		eft = 5
+		kind = "条件"
+	}
+	result.Effect = EftAllow
+	result.Reason = "命中" + kind + "允许策略"
+	if len(policy) > eft && policy[eft] == EftDeny {
+		result.Effect = EftDeny
+		result.Reason = "命中" + kind + "拒绝策略"
+	}
+	return result, nil
+}
+
+// roleChain 返回从用户到策略主体的继承链
+func roleChain(username, subject string, roles []RoleSource) []string {
+	if subject == username {
+		return []string{username}
+	}
+	for _, source := range roles {
+		if source.Role != subject {
+			continue
+		}
+		chain := append([]string{username}, source.Via...)
+		return append(chain, subject)
+	}
+	return []string{username, subject}
+}
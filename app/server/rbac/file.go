@@ -0,0 +1,214 @@
+package rbac
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// 策略文件支持 Casbin 的 CSV 格式（与 configs/rbac_policy.csv 相同），p 规则的 eft、priority 可省略：
+//
+//	p, admin, *, /api/users, GET
+//	p, contractor, *, /api/users/:id, DELETE, deny, 10
+//	p2, user, *, /api/users/:id, GET, r2.res.OwnerID == r2.user.ID, allow
+//	g, alice, admin, *
+//
+// 以及等价的 YAML 格式（扩展名为 .yaml 或 .yml）：
+//
+//	policies:
+//	  - {sub: admin, dom: "*", obj: /api/users, act: GET}
+//	  - {sub: contractor, obj: "/api/users/:id", act: DELETE, eft: deny, priority: 10}
+//	  - {sub: user, obj: "/api/users/:id", act: GET, cond: "r2.res.OwnerID == r2.user.ID"}
+//	roles:
+//	  - {user: alice, role: admin, dom: "*"}
+
+// PolicySet 策略文件中的全部规则
+type PolicySet struct {
+	Policies    [][]string // p 规则
+	Conditional [][]string // p2 规则
+	Groupings   [][]string // g 规则
+}
+
+// add 按策略类型加入规则
+func (set *PolicySet) add(policy Policy) {
+	if policy.Ptype() == ConditionalPtype {
+		set.Conditional = append(set.Conditional, policy.Rule())
+	} else {
+		set.Policies = append(set.Policies, policy.Rule())
+	}
+}
+
+// RoleAssignment 策略文件中的一条角色分配（g 规则）
+type RoleAssignment struct {
+	User   string `yaml:"user"`
+	Role   string `yaml:"role"`
+	Domain string `yaml:"dom"`
+}
+
+// policyDocument YAML 策略文件的结构
+type policyDocument struct {
+	Policies []Policy         `yaml:"policies"`
+	Roles    []RoleAssignment `yaml:"roles"`
+}
+
+// LoadPolicyFile 读取策略文件，按扩展名选择 CSV 或 YAML 格式
+func LoadPolicyFile(path string) (*PolicySet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ReadPoliciesYAML(file)
+	default:
+		return ReadPolicies(file)
+	}
+}
+
+// ReadPoliciesYAML 解析 YAML 格式的策略文件，未指定域的规则归入全局域
+func ReadPoliciesYAML(r io.Reader) (*PolicySet, error) {
+	var doc policyDocument
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	set := &PolicySet{}
+	for i, policy := range doc.Policies {
+		if policy.Subject == "" || policy.Object == "" || policy.Action == "" {
+			return nil, fmt.Errorf("policies[%d]: sub、obj、act 不能为空", i)
+		}
+		if policy.Effect != "" && !ValidEft(policy.Effect) {
+			return nil, fmt.Errorf("policies[%d]: eft 只能为 allow 或 deny", i)
+		}
+		set.add(policy)
+	}
+	for i, role := range doc.Roles {
+		if role.User == "" || role.Role == "" {
+			return nil, fmt.Errorf("roles[%d]: user、role 不能为空", i)
+		}
+		domain := role.Domain
+		if domain == "" {
+			domain = GlobalDomain
+		}
+		set.Groupings = append(set.Groupings, []string{role.User, role.Role, domain})
+	}
+	return set, nil
+}
+
+// WritePolicies 以 CSV 格式写出当前的全部 p、p2 和 g 规则
+func WritePolicies(w io.Writer) error {
+	set := &PolicySet{}
+	var err error
+	if set.Policies, err = Enforcer.GetPolicy(); err != nil {
+		return err
+	}
+	if set.Conditional, err = Enforcer.GetNamedPolicy(ConditionalPtype); err != nil {
+		return err
+	}
+	if set.Groupings, err = Enforcer.GetGroupingPolicy(); err != nil {
+		return err
+	}
+	return set.Write(w)
+}
+
+// Write 以 CSV 格式写出规则集中的 p、p2 和 g 规则
+func (set *PolicySet) Write(w io.Writer) error {
+	groups := []struct {
+		ptype string
+		rules [][]string
+	}{
+		{"p", set.Policies},
+		{ConditionalPtype, set.Conditional},
+		{"g", set.Groupings},
+	}
+	for _, group := range groups {
+		for _, rule := range group.rules {
+			fields := make([]string, 0, len(rule)+1)
+			fields = append(fields, group.ptype)
+			for _, field := range rule {
+				fields = append(fields, quoteField(field))
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(fields, ", ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// quoteField 按 CSV 规则为含逗号或引号的字段（通常是条件表达式）加引号
+func quoteField(field string) string {
+	if !strings.ContainsAny(field, ",\"\n") {
+		return field
+	}
+	return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+}
+
+// ReadPolicies 解析 CSV 格式的策略文件
+// 空行和以 # 开头的行会被忽略；含逗号的条件表达式需用双引号括起
+func ReadPolicies(r io.Reader) (*PolicySet, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	set := &PolicySet{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		switch record[0] {
+		case "p":
+			if len(record) < 5 || len(record) > 7 {
+				return nil, fmt.Errorf("第 %d 行: p 规则应为 p, sub, dom, obj, act[, eft[, priority]]", line)
+			}
+			policy := Policy{Subject: record[1], Domain: record[2], Object: record[3], Action: record[4]}
+			if len(record) > 5 {
+				policy.Effect = record[5]
+			}
+			if len(record) > 6 {
+				if policy.Priority, err = strconv.Atoi(record[6]); err != nil {
+					return nil, fmt.Errorf("第 %d 行: 无效的优先级 %q", line, record[6])
+				}
+			}
+			if policy.Effect != "" && !ValidEft(policy.Effect) {
+				return nil, fmt.Errorf("第 %d 行: eft 只能为 allow 或 deny", line)
+			}
+			set.add(policy)
+		case ConditionalPtype:
+			if len(record) != 7 || record[5] == "" {
+				return nil, fmt.Errorf("第 %d 行: p2 规则应为 p2, sub, dom, obj, act, cond, eft", line)
+			}
+			if !ValidEft(record[6]) {
+				return nil, fmt.Errorf("第 %d 行: eft 只能为 allow 或 deny", line)
+			}
+			set.add(Policy{Subject: record[1], Domain: record[2], Object: record[3], Action: record[4], Condition: record[5], Effect: record[6]})
+		case "g":
+			if len(record) != 4 {
+				return nil, fmt.Errorf("第 %d 行: g 规则应为 g, user, role, dom", line)
+			}
+			set.Groupings = append(set.Groupings, record[1:])
+		default:
+			return nil, fmt.Errorf("第 %d 行: 未知的规则类型 %q", line, record[0])
+		}
+	}
+	return set, nil
+}
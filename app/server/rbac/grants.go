@@ -0,0 +1,138 @@
+package rbac
+
+import (
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/util"
+)
+
+// GrantWindow 限时角色授权的有效期，ExpiresAt 为零值时不会到期
+type GrantWindow struct {
+	NotBefore *time.Time
+	ExpiresAt time.Time
+}
+
+// Active 指定时间是否处于有效期内
+func (w GrantWindow) Active(now time.Time) bool {
+	if w.NotBefore != nil && now.Before(*w.NotBefore) {
+		return false
+	}
+	return w.ExpiresAt.IsZero() || now.Before(w.ExpiresAt)
+}
+
+// TimedGrant 一条带有效期的 g 规则（user, role, dom）
+type TimedGrant struct {
+	User   string
+	Role   string
+	Domain string
+	GrantWindow
+}
+
+type grantKey struct {
+	user, role, domain string
+}
+
+var (
+	grantWindowsMu sync.RWMutex
+	grantWindows   = make(map[grantKey]GrantWindow)
+)
+
+// SetGrantWindow 为 g 规则设置有效期，有效期外该规则在权限判定时不生效
+func SetGrantWindow(grant TimedGrant) {
+	grantWindowsMu.Lock()
+	defer grantWindowsMu.Unlock()
+	grantWindows[grantKey{grant.User, grant.Role, grant.Domain}] = grant.GrantWindow
+}
+
+// ClearGrantWindow 清除 g 规则的有效期
+func ClearGrantWindow(user, role, domain string) {
+	grantWindowsMu.Lock()
+	defer grantWindowsMu.Unlock()
+	delete(grantWindows, grantKey{user, role, domain})
+}
+
+// ReplaceGrantWindows 用数据库中的全部限时授权替换内存中的有效期（定时刷新，使多个副本保持一致）
+func ReplaceGrantWindows(grants []TimedGrant) {
+	windows := make(map[grantKey]GrantWindow, len(grants))
+	for _, grant := range grants {
+		windows[grantKey{grant.User, grant.Role, grant.Domain}] = grant.GrantWindow
+	}
+
+	grantWindowsMu.Lock()
+	defer grantWindowsMu.Unlock()
+	grantWindows = windows
+}
+
+// inactiveGrant 判断 child -> parent 的 g 规则是否为不在有效期内的限时授权
+func inactiveGrant(child, parent, domain string, now time.Time) bool {
+	grantWindowsMu.RLock()
+	defer grantWindowsMu.RUnlock()
+	w, ok := grantWindows[grantKey{child, parent, domain}]
+	return ok && !w.Active(now)
+}
+
+// hasInactiveGrant 用户是否有不在有效期内的限时授权
+func hasInactiveGrant(username string, now time.Time) bool {
+	grantWindowsMu.RLock()
+	defer grantWindowsMu.RUnlock()
+	for key, w := range grantWindows {
+		if key.user == username && !w.Active(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveRoles 从 roles 中去掉仅经由未生效（尚未开始或已到期）的限时授权获得的角色
+// Token 中的角色在签发时确定，需要在每次权限判定时用它过滤，使到期的临时角色立即失效
+func ActiveRoles(username, domain string, roles []string) ([]string, error) {
+	now := time.Now()
+	if !hasInactiveGrant(username, now) {
+		return roles, nil
+	}
+
+	rules, err := Enforcer.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+	all := reachableSubjects(username, domain, rules, nil)
+	active := reachableSubjects(username, domain, rules, func(child, parent, ruleDomain string) bool {
+		return inactiveGrant(child, parent, ruleDomain, now)
+	})
+
+	filtered := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if active[role] || !all[role] {
+			filtered = append(filtered, role)
+		}
+	}
+	return filtered, nil
+}
+
+// reachableSubjects 返回从 subject 出发经由在当前域中生效的 g 规则可达的全部主体，skip 返回 true 的规则会被忽略
+func reachableSubjects(subject, domain string, rules [][]string, skip func(child, parent, domain string) bool) map[string]bool {
+	edges := make(map[string][][2]string)
+	for _, rule := range rules {
+		if len(rule) < 3 || !util.KeyMatch(domain, rule[2]) {
+			continue
+		}
+		edges[rule[0]] = append(edges[rule[0]], [2]string{rule[1], rule[2]})
+	}
+
+	reached := make(map[string]bool)
+	queue := []string{subject}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, edge := range edges[current] {
+			parent, ruleDomain := edge[0], edge[1]
+			if reached[parent] || (skip != nil && skip(current, parent, ruleDomain)) {
+				continue
+			}
+			reached[parent] = true
+			queue = append(queue, parent)
+		}
+	}
+	return reached
+}
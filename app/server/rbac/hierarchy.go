@@ -0,0 +1,79 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxRoleDepth 角色继承链的最大层数，与 Casbin 默认角色管理器解析的层数一致，超出部分会被静默忽略
+const MaxRoleDepth = 10
+
+var (
+	// ErrRoleCycle 角色继承会形成循环
+	ErrRoleCycle = errors.New("角色继承存在循环")
+	// ErrRoleTooDeep 角色继承层数过多
+	ErrRoleTooDeep = fmt.Errorf("角色继承层数不能超过 %d", MaxRoleDepth)
+)
+
+// CheckInheritance 检查让 child 继承 parent（g, child, parent, *）是否会形成循环或超出层数
+// 基于 Casbin 中全局域的全部 g 规则判断，因此也能发现策略文件等其他来源引入的继承关系
+func CheckInheritance(child, parent string) error {
+	if child == parent {
+		return ErrRoleCycle
+	}
+
+	rules, err := Enforcer.GetFilteredGroupingPolicy(2, GlobalDomain)
+	if err != nil {
+		return err
+	}
+	up := make(map[string][]string)
+	down := make(map[string][]string)
+	for _, rule := range rules {
+		up[rule[0]] = append(up[rule[0]], rule[1])
+		down[rule[1]] = append(down[rule[1]], rule[0])
+	}
+
+	if reachableSubjects(parent, GlobalDomain, rules, nil)[child] {
+		return ErrRoleCycle
+	}
+	if longestPath(parent, up)+longestPath(child, down)+1 > MaxRoleDepth {
+		return ErrRoleTooDeep
+	}
+	return nil
+}
+
+// RoleDepth 返回 role 向上的最长继承链层数（不含 role 自身），供用户组等其他继承链合并计算总层数
+func RoleDepth(role string) (int, error) {
+	rules, err := Enforcer.GetFilteredGroupingPolicy(2, GlobalDomain)
+	if err != nil {
+		return 0, err
+	}
+	up := make(map[string][]string)
+	for _, rule := range rules {
+		up[rule[0]] = append(up[rule[0]], rule[1])
+	}
+	return longestPath(role, up), nil
+}
+
+// longestPath 返回从 subject 出发沿 edges 的最长路径长度（边数），edges 需无环
+func longestPath(subject string, edges map[string][]string) int {
+	memo := make(map[string]int)
+	var walk func(node string, visiting map[string]bool) int
+	walk = func(node string, visiting map[string]bool) int {
+		if depth, ok := memo[node]; ok {
+			return depth
+		}
+		visiting[node] = true
+		longest := 0
+		for _, next := range edges[node] {
+			if visiting[next] {
+				continue
+			}
+			longest = max(longest, walk(next, visiting)+1)
+		}
+		delete(visiting, node)
+		memo[node] = longest
+		return longest
+	}
+	return walk(subject, make(map[string]bool))
+}
@@ -0,0 +1,197 @@
+package rbac
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// 批量变更策略
+// 规则经适配器的批量接口写入数据库：新增为单条 INSERT，删除在同一事务中完成，
+// 因此一批规则要么全部生效要么全部失败，并发编辑时也不会互相覆盖整张表。
+
+// AddPolicies 批量添加策略规则（sub, dom, obj, act），已存在的规则会被跳过，返回实际新增的条数
+func AddPolicies(rules [][]string) (int, error) {
+	return addRules(rules, Enforcer.HasPolicy, Enforcer.AddPolicies)
+}
+
+// RemovePolicies 批量删除策略规则，不存在的规则会被跳过，返回实际删除的条数
+func RemovePolicies(rules [][]string) (int, error) {
+	return removeRules(rules, Enforcer.HasPolicy, Enforcer.RemovePolicies)
+}
+
+// AddGroupingPolicies 批量添加 g 规则（child, parent, dom），已存在的规则会被跳过，返回实际新增的条数
+func AddGroupingPolicies(rules [][]string) (int, error) {
+	return addRules(rules, Enforcer.HasGroupingPolicy, Enforcer.AddGroupingPolicies)
+}
+
+// RemoveGroupingPolicies 批量删除 g 规则，不存在的规则会被跳过，返回实际删除的条数
+func RemoveGroupingPolicies(rules [][]string) (int, error) {
+	return removeRules(rules, Enforcer.HasGroupingPolicy, Enforcer.RemoveGroupingPolicies)
+}
+
+// maxBatchAttempts 批量变更因并发修改被 Casbin 整体放弃时的最多尝试次数
+const maxBatchAttempts = 3
+
+// ErrConcurrentPolicyChange 策略被并发修改，多次重试后批量变更仍未能应用
+var ErrConcurrentPolicyChange = errors.New("策略被并发修改，请重试")
+
+// addRules 过滤掉已存在和重复的规则后批量添加
+// Casbin 的批量接口在任一规则已存在时会整体放弃，因此需要先过滤
+func addRules(rules [][]string, has func(...interface{}) (bool, error), add func([][]string) (bool, error)) (int, error) {
+	return applyRules(rules, has, false, add)
+}
+
+// removeRules 过滤掉不存在和重复的规则后批量删除
+func removeRules(rules [][]string, has func(...interface{}) (bool, error), remove func([][]string) (bool, error)) (int, error) {
+	return applyRules(rules, has, true, remove)
+}
+
+// applyRules 筛选出需要变更的规则后整批应用
+// 筛选和应用是两次独立的加锁操作，期间其他请求可能已增删了其中的规则，此时 Casbin 返回 false 且不做任何变更，
+// 重新筛选后再试
+func applyRules(rules [][]string, has func(...interface{}) (bool, error), exists bool, apply func([][]string) (bool, error)) (int, error) {
+	for range maxBatchAttempts {
+		pending, err := filterRules(rules, has, exists)
+		if err != nil || len(pending) == 0 {
+			return 0, err
+		}
+		ok, err := apply(pending)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return len(pending), nil
+		}
+	}
+	return 0, ErrConcurrentPolicyChange
+}
+
+// filterRules 按规则是否已存在筛选，同时去除批次内的重复规则
+func filterRules(rules [][]string, has func(...interface{}) (bool, error), exists bool) ([][]string, error) {
+	seen := make(map[string]bool, len(rules))
+	pending := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		key := strings.Join(rule, ",")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		ok, err := has(rule)
+		if err != nil {
+			return nil, err
+		}
+		if ok == exists {
+			pending = append(pending, rule)
+		}
+	}
+	return pending, nil
+}
+
+// Policy 一条策略规则：普通策略（p）或条件策略（p2，Condition 非空）
+type Policy struct {
+	Subject   string `json:"sub" yaml:"sub"`
+	Domain    string `json:"dom" yaml:"dom"`
+	Object    string `json:"obj" yaml:"obj"`
+	Action    string `json:"act" yaml:"act"`
+	Effect    string `json:"eft" yaml:"eft"`                       // allow 或 deny
+	Priority  int    `json:"priority,omitempty" yaml:"priority"`   // 数值越小优先级越高，仅在优先级模式下生效；0 表示默认值
+	Condition string `json:"cond,omitempty" yaml:"cond,omitempty"` // 条件表达式，见 ConditionalPtype
+}
+
+// Ptype 返回策略的规则类型
+func (p Policy) Ptype() string {
+	if p.Condition != "" {
+		return ConditionalPtype
+	}
+	return "p"
+}
+
+// Rule 转换为 Casbin 规则，未指定的字段取默认值（全局域、allow、默认优先级）
+func (p Policy) Rule() []string {
+	domain := p.Domain
+	if domain == "" {
+		domain = GlobalDomain
+	}
+	eft := p.Effect
+	if eft == "" {
+		eft = EftAllow
+	}
+	if p.Condition != "" {
+		return []string{p.Subject, domain, p.Object, p.Action, p.Condition, eft}
+	}
+	priority := p.Priority
+	if priority == 0 {
+		priority = DefaultPriority
+	}
+	return []string{p.Subject, domain, p.Object, p.Action, eft, strconv.Itoa(priority)}
+}
+
+// SplitPolicies 将策略按类型转换为普通策略规则和条件策略规则
+func SplitPolicies(policies []Policy) (rules, conditional [][]string) {
+	for _, policy := range policies {
+		if policy.Ptype() == ConditionalPtype {
+			conditional = append(conditional, policy.Rule())
+		} else {
+			rules = append(rules, policy.Rule())
+		}
+	}
+	return rules, conditional
+}
+
+// policyFromRule 由 Casbin 规则构造 Policy
+func policyFromRule(ptype string, rule []string) Policy {
+	var p Policy
+	fields := []*string{&p.Subject, &p.Domain, &p.Object, &p.Action, &p.Effect}
+	if ptype == ConditionalPtype {
+		fields = []*string{&p.Subject, &p.Domain, &p.Object, &p.Action, &p.Condition, &p.Effect}
+	}
+	for i := 0; i < len(fields) && i < len(rule); i++ {
+		*fields[i] = rule[i]
+	}
+	if ptype != ConditionalPtype {
+		p.Priority = rulePriority(rule)
+	}
+	return p
+}
+
+// ListPolicies 按条件列出普通策略和条件策略，filter 中为空的字段不参与过滤（Priority、Condition 不参与过滤）
+func ListPolicies(filter Policy) ([]Policy, error) {
+	rules, err := Enforcer.GetFilteredPolicy(0, filter.Subject, filter.Domain, filter.Object, filter.Action, filter.Effect)
+	if err != nil {
+		return nil, err
+	}
+	conditional, err := Enforcer.GetFilteredNamedPolicy(ConditionalPtype, 0, filter.Subject, filter.Domain, filter.Object, filter.Action, "", filter.Effect)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make([]Policy, 0, len(rules)+len(conditional))
+	for _, rule := range rules {
+		policies = append(policies, policyFromRule("p", rule))
+	}
+	for _, rule := range conditional {
+		policies = append(policies, policyFromRule(ConditionalPtype, rule))
+	}
+	return policies, nil
+}
+
+// UpdatePolicy 将一条策略替换为另一条，原策略不存在时返回 false
+// 普通策略和条件策略之间不能互相修改
+func UpdatePolicy(oldPolicy, newPolicy Policy) (bool, error) {
+	if oldPolicy.Ptype() != newPolicy.Ptype() {
+		return false, errors.New("普通策略与条件策略不能互相修改，请先删除再添加")
+	}
+	return Enforcer.UpdateNamedPolicy(oldPolicy.Ptype(), oldPolicy.Rule(), newPolicy.Rule())
+}
+
+// GetMembersForRole 获取在指定域中被直接授予该角色的主体（用户或用户组）
+func GetMembersForRole(role, domain string) []string {
+	rules, _ := Enforcer.GetFilteredGroupingPolicy(1, role, domain)
+	members := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		members = append(members, rule[0])
+	}
+	return members
+}
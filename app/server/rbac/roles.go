@@ -0,0 +1,148 @@
+package rbac
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/casbin/casbin/v2/util"
+)
+
+// groupPrefix 用户组在 Casbin 中的主体前缀，如 group:3
+const groupPrefix = "group:"
+
+// DefaultRole 在全局域中没有任何角色的用户登录时获得的默认角色
+const DefaultRole = "user"
+
+// GroupSubject 返回用户组在 Casbin 中的主体名
+func GroupSubject(groupID uint) string {
+	return fmt.Sprintf("%s%d", groupPrefix, groupID)
+}
+
+// IsGroupSubject 判断主体是否为用户组
+func IsGroupSubject(subject string) bool {
+	return strings.HasPrefix(subject, groupPrefix)
+}
+
+// AddLink 添加一条 g 规则：child 继承 parent（用户加入组、组授予角色、子组挂到父组均使用该规则）
+func AddLink(child, parent, domain string) (bool, error) {
+	return Enforcer.AddGroupingPolicy(child, parent, domain)
+}
+
+// RemoveLink 删除一条 g 规则
+func RemoveLink(child, parent, domain string) (bool, error) {
+	return Enforcer.RemoveGroupingPolicy(child, parent, domain)
+}
+
+// HasLink 检查 g 规则是否存在
+func HasLink(child, parent, domain string) (bool, error) {
+	return Enforcer.HasGroupingPolicy(child, parent, domain)
+}
+
+// GetParents 获取主体在指定域中直接继承的对象（角色或用户组）
+func GetParents(subject, domain string) []string {
+	rules, _ := Enforcer.GetFilteredGroupingPolicy(0, subject, "", domain)
+	parents := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		parents = append(parents, rule[1])
+	}
+	return parents
+}
+
+// DeleteSubject 删除主体相关的全部 g 规则和策略（两端均会被清理）
+func DeleteSubject(subject string) (bool, error) {
+	return Enforcer.DeleteRole(subject)
+}
+
+// GetImplicitRolesForUser 获取用户在指定域中的全部有效角色（含经由用户组等间接获得的角色，不含用户组本身）
+// 不在有效期内的限时授权不计入
+func GetImplicitRolesForUser(username, domain string) ([]string, error) {
+	subjects, err := Enforcer.GetImplicitRolesForUser(username, domain)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]string, 0, len(subjects))
+	for _, subject := range subjects {
+		if !IsGroupSubject(subject) {
+			roles = append(roles, subject)
+		}
+	}
+	return ActiveRoles(username, domain, roles)
+}
+
+// ResolveRoles 按登录和租户中间件的方式计算用户在指定域中参与判定的角色：
+// 全局域中的有效角色（没有时为 DefaultRole），进入租户时再合并该租户中的有效角色，并去掉不在有效期内的限时角色
+func ResolveRoles(username, domain string) ([]string, error) {
+	roles, err := GetImplicitRolesForUser(username, GlobalDomain)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		roles = []string{DefaultRole}
+	}
+	if domain == "" || domain == GlobalDomain {
+		return ActiveRoles(username, GlobalDomain, roles)
+	}
+
+	tenantRoles, err := GetImplicitRolesForUser(username, domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range tenantRoles {
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return ActiveRoles(username, domain, roles)
+}
+
+// RoleSource 角色来源
+type RoleSource struct {
+	Role   string   `json:"role"`
+	Domain string   `json:"domain"`        // 授予该角色的 g 规则所在的域
+	Source string   `json:"source"`        // direct: 直接授予；inherited: 经由用户组或其他角色继承
+	Via    []string `json:"via,omitempty"` // 继承链上的中间主体，按从用户到角色的顺序
+}
+
+// ExplainRoles 列出用户在指定域中的所有有效角色及其来源
+// 同一角色可能经由多条路径获得，此时每条路径各返回一条记录
+func ExplainRoles(username, domain string) ([]RoleSource, error) {
+	rules, err := Enforcer.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	// 按子主体建立邻接表，仅保留在当前域中生效的规则
+	edges := make(map[string][][2]string)
+	for _, rule := range rules {
+		if len(rule) < 3 || !util.KeyMatch(domain, rule[2]) {
+			continue
+		}
+		edges[rule[0]] = append(edges[rule[0]], [2]string{rule[1], rule[2]})
+	}
+
+	var sources []RoleSource
+	var walk func(subject string, path []string, visited map[string]bool)
+	walk = func(subject string, path []string, visited map[string]bool) {
+		for _, edge := range edges[subject] {
+			parent, ruleDomain := edge[0], edge[1]
+			if visited[parent] {
+				continue
+			}
+			if !IsGroupSubject(parent) {
+				source := RoleSource{Role: parent, Domain: ruleDomain, Source: "direct"}
+				if len(path) > 0 {
+					source.Source = "inherited"
+					source.Via = append([]string{}, path...)
+				}
+				sources = append(sources, source)
+			}
+			visited[parent] = true
+			walk(parent, append(path, parent), visited)
+			delete(visited, parent)
+		}
+	}
+	walk(username, nil, map[string]bool{username: true})
+
+	return sources, nil
+}
@@ -0,0 +1,160 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ErrEmptyPolicyFile 策略文件中没有任何 p 规则，按文件同步会清空全部权限
+var ErrEmptyPolicyFile = errors.New("策略文件中没有任何 p 规则，拒绝同步")
+
+// PolicyDiff 策略文件与当前策略的差异
+type PolicyDiff struct {
+	AddPolicies       [][]string `json:"add_policies"`
+	RemovePolicies    [][]string `json:"remove_policies"`
+	AddConditional    [][]string `json:"add_conditional"`
+	RemoveConditional [][]string `json:"remove_conditional"`
+	AddGroupings      [][]string `json:"add_groupings"`
+	RemoveGroupings   [][]string `json:"remove_groupings"`
+}
+
+// Empty 是否没有任何差异
+func (d *PolicyDiff) Empty() bool {
+	return len(d.AddPolicies) == 0 && len(d.RemovePolicies) == 0 &&
+		len(d.AddConditional) == 0 && len(d.RemoveConditional) == 0 &&
+		len(d.AddGroupings) == 0 && len(d.RemoveGroupings) == 0
+}
+
+// Print 以 +/- 的形式输出计划中的变更
+func (d *PolicyDiff) Print(w io.Writer) error {
+	lines := make([]string, 0)
+	for _, rule := range d.RemovePolicies {
+		lines = append(lines, "- p, "+strings.Join(rule, ", "))
+	}
+	for _, rule := range d.AddPolicies {
+		lines = append(lines, "+ p, "+strings.Join(rule, ", "))
+	}
+	for _, rule := range d.RemoveConditional {
+		lines = append(lines, "- p2, "+strings.Join(rule, ", "))
+	}
+	for _, rule := range d.AddConditional {
+		lines = append(lines, "+ p2, "+strings.Join(rule, ", "))
+	}
+	for _, rule := range d.RemoveGroupings {
+		lines = append(lines, "- g, "+strings.Join(rule, ", "))
+	}
+	for _, rule := range d.AddGroupings {
+		lines = append(lines, "+ g, "+strings.Join(rule, ", "))
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "p: +%d -%d, p2: +%d -%d, g: +%d -%d\n",
+		len(d.AddPolicies), len(d.RemovePolicies), len(d.AddConditional), len(d.RemoveConditional),
+		len(d.AddGroupings), len(d.RemoveGroupings))
+	return err
+}
+
+// DiffPolicies 计算使当前策略与目标规则一致所需的变更
+// p、p2 规则以目标为准（多删少补），但内置的默认策略（见 DefaultPolicies）不会被删除，
+// 否则文件缺少任何一条都会让租户、用户组等内置接口失去权限；
+// g 规则默认只补不删，因为角色分配还会通过租户、用户组等接口在运行时维护，
+// pruneRoles 为 true 时才删除目标中不存在的 g 规则
+func DiffPolicies(set *PolicySet, pruneRoles bool) (*PolicyDiff, error) {
+	if len(set.Policies) == 0 {
+		return nil, ErrEmptyPolicyFile
+	}
+
+	current, err := Enforcer.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+	currentConditional, err := Enforcer.GetNamedPolicy(ConditionalPtype)
+	if err != nil {
+		return nil, err
+	}
+	currentGroupings, err := Enforcer.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &PolicyDiff{}
+	diff.AddPolicies, diff.RemovePolicies = diffRules(current, set.Policies)
+	diff.AddConditional, diff.RemoveConditional = diffRules(currentConditional, set.Conditional)
+	diff.AddGroupings, diff.RemoveGroupings = diffRules(currentGroupings, set.Groupings)
+	if !pruneRoles {
+		diff.RemoveGroupings = nil
+	}
+
+	defaults := DefaultPolicies()
+	_, diff.RemovePolicies = diffRules(diff.RemovePolicies, defaults.Policies)
+	_, diff.RemoveConditional = diffRules(diff.RemoveConditional, defaults.Conditional)
+	return diff, nil
+}
+
+// ApplyDiff 应用策略差异，先删后增
+func ApplyDiff(diff *PolicyDiff) error {
+	if _, err := RemovePolicies(diff.RemovePolicies); err != nil {
+		return fmt.Errorf("删除策略失败: %w", err)
+	}
+	if _, err := AddPolicies(diff.AddPolicies); err != nil {
+		return fmt.Errorf("添加策略失败: %w", err)
+	}
+	if _, err := RemoveConditionalPolicies(diff.RemoveConditional); err != nil {
+		return fmt.Errorf("删除条件策略失败: %w", err)
+	}
+	if _, err := AddConditionalPolicies(diff.AddConditional); err != nil {
+		return fmt.Errorf("添加条件策略失败: %w", err)
+	}
+	if _, err := RemoveGroupingPolicies(diff.RemoveGroupings); err != nil {
+		return fmt.Errorf("删除角色分配失败: %w", err)
+	}
+	if _, err := AddGroupingPolicies(diff.AddGroupings); err != nil {
+		return fmt.Errorf("添加角色分配失败: %w", err)
+	}
+	return nil
+}
+
+// SyncPolicyFile 按策略文件同步策略，返回已应用的差异
+func SyncPolicyFile(path string, pruneRoles bool) (*PolicyDiff, error) {
+	set, err := LoadPolicyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := DiffPolicies(set, pruneRoles)
+	if err != nil {
+		return nil, err
+	}
+	if diff.Empty() {
+		return diff, nil
+	}
+	return diff, ApplyDiff(diff)
+}
+
+// diffRules 返回 want 中有而 have 中没有的规则，以及 have 中有而 want 中没有的规则
+func diffRules(have, want [][]string) (add, remove [][]string) {
+	haveSet := make(map[string]bool, len(have))
+	for _, rule := range have {
+		haveSet[strings.Join(rule, ",")] = true
+	}
+	wantSet := make(map[string]bool, len(want))
+	for _, rule := range want {
+		key := strings.Join(rule, ",")
+		if !wantSet[key] && !haveSet[key] {
+			add = append(add, rule)
+		}
+		wantSet[key] = true
+	}
+	for _, rule := range have {
+		if !wantSet[strings.Join(rule, ",")] {
+			remove = append(remove, slices.Clone(rule))
+		}
+	}
+	return add, remove
+}
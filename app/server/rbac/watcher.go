@@ -0,0 +1,357 @@
+package rbac
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/redis/go-redis/v9"
+)
+
+// 策略变更消息类型
+const (
+	opAddPolicies    = "add_policies"
+	opRemovePolicies = "remove_policies"
+	opRemoveFiltered = "remove_filtered"
+	opReload         = "reload"
+)
+
+// policyVersion 本节点当前运行的策略版本（与 Redis 中的全局版本号对应）
+var policyVersion atomic.Int64
+
+// lastReload 最近一次全量加载策略的时间（Unix 秒）
+var lastReload atomic.Int64
+
+// remoteUpdateHook 应用其他节点的策略变更（增量或全量加载）后调用，见 OnRemoteUpdate
+var remoteUpdateHook atomic.Pointer[func()]
+
+// OnRemoteUpdate 注册应用其他节点的策略变更后调用的函数
+// 用于刷新只保存在本节点内存中、需与策略一起变化的状态，例如限时授权的有效期
+func OnRemoteUpdate(fn func()) {
+	remoteUpdateHook.Store(&fn)
+}
+
+// runRemoteUpdateHook 调用 OnRemoteUpdate 注册的函数
+func runRemoteUpdateHook() {
+	if fn := remoteUpdateHook.Load(); fn != nil {
+		(*fn)()
+	}
+}
+
+func init() {
+	expvar.Publish("casbin_policy_version", expvar.Func(func() any { return policyVersion.Load() }))
+	expvar.Publish("casbin_policy_last_reload", expvar.Func(func() any { return lastReload.Load() }))
+}
+
+// PolicyLoaded 策略是否已从数据库加载
+func PolicyLoaded() bool {
+	return Enforcer != nil && lastReload.Load() > 0
+}
+
+// PolicyVersion 返回本节点当前运行的策略版本
+func PolicyVersion() int64 {
+	return policyVersion.Load()
+}
+
+// policyMessage 通过 Redis 广播的策略变更
+type policyMessage struct {
+	Instance    string     `json:"instance"`
+	Version     int64      `json:"version"`
+	Op          string     `json:"op"`
+	Sec         string     `json:"sec,omitempty"`
+	Ptype       string     `json:"ptype,omitempty"`
+	Rules       [][]string `json:"rules,omitempty"`
+	FieldIndex  int        `json:"field_index,omitempty"`
+	FieldValues []string   `json:"field_values,omitempty"`
+}
+
+// RedisWatcher 基于 Redis pub/sub 的 Casbin 策略同步器
+// 本节点的每次策略变更都会递增 Redis 中的全局版本号并广播增量；其他节点收到后直接应用到内存，
+// 若发现版本号不连续（漏收消息）则退化为全量加载。另有定时全量加载作为兜底。
+type RedisWatcher struct {
+	client     *redis.Client
+	channel    string
+	versionKey string
+	instance   string
+
+	pubsub   *redis.PubSub
+	callback func(string)
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewRedisWatcher 创建策略同步器
+func NewRedisWatcher(client *redis.Client, channel string) *RedisWatcher {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+
+	return &RedisWatcher{
+		client:     client,
+		channel:    channel,
+		versionKey: channel + ":version",
+		instance:   hex.EncodeToString(buf),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start 订阅策略变更，并按 reloadInterval 定时全量加载（为 0 时不定时加载）
+func (w *RedisWatcher) Start(reloadInterval time.Duration) {
+	w.pubsub = w.client.Subscribe(context.Background(), w.channel)
+	messages := w.pubsub.Channel()
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for msg := range messages {
+			w.handle(msg.Payload)
+		}
+	}()
+
+	if reloadInterval <= 0 {
+		return
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(reloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := reloadPolicy(w.currentVersion()); err != nil {
+					slog.Error("定时全量加载策略失败", "error", err)
+				}
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// currentVersion 读取 Redis 中的全局策略版本
+func (w *RedisWatcher) currentVersion() int64 {
+	value, err := w.client.Get(context.Background(), w.versionKey).Result()
+	if err != nil {
+		return policyVersion.Load()
+	}
+	version, _ := strconv.ParseInt(value, 10, 64)
+	return version
+}
+
+// handle 处理其他节点广播的策略变更
+func (w *RedisWatcher) handle(payload string) {
+	var msg policyMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		slog.Warn("忽略无法解析的策略变更消息", "error", err)
+		return
+	}
+	if msg.Instance == w.instance {
+		return
+	}
+
+	// 版本不连续说明漏收了消息，增量无法保证正确，改为全量加载
+	if msg.Op == opReload || msg.Version != policyVersion.Load()+1 {
+		if err := reloadPolicy(msg.Version); err != nil {
+			slog.Error("全量加载策略失败", "error", err)
+		}
+		return
+	}
+
+	err := applyRemote(func(e *casbin.Enforcer) error {
+		var err error
+		switch msg.Op {
+		case opAddPolicies:
+			_, err = e.SelfAddPoliciesEx(msg.Sec, msg.Ptype, msg.Rules)
+		case opRemovePolicies:
+			_, err = e.SelfRemovePolicies(msg.Sec, msg.Ptype, msg.Rules)
+		case opRemoveFiltered:
+			_, err = e.SelfRemoveFilteredPolicy(msg.Sec, msg.Ptype, msg.FieldIndex, msg.FieldValues...)
+		default:
+			err = errors.New("未知的策略变更类型: " + msg.Op)
+		}
+		return err
+	})
+	if err != nil {
+		slog.Error("应用策略增量失败，改为全量加载", "op", msg.Op, "error", err)
+		if err := reloadPolicy(msg.Version); err != nil {
+			slog.Error("全量加载策略失败", "error", err)
+		}
+		return
+	}
+	invalidateDecisions()
+	runRemoteUpdateHook()
+
+	policyVersion.Store(msg.Version)
+	if w.callback != nil {
+		w.callback(payload)
+	}
+}
+
+// applyRemote 只在内存中应用其他节点的策略变更
+// 数据库已由发起变更的节点写入，各副本重复写库会与后续变更交错，例如迟到的添加会恢复刚删除的规则。
+// 持有执行器写锁期间关闭自动保存，本节点的其他策略变更同样需要该锁，不会在此期间漏写数据库
+func applyRemote(fn func(e *casbin.Enforcer) error) error {
+	lock := Enforcer.GetLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	Enforcer.Enforcer.EnableAutoSave(false)
+	defer Enforcer.Enforcer.EnableAutoSave(true)
+	return fn(Enforcer.Enforcer)
+}
+
+// publish 递增全局版本号并广播变更
+// Casbin 在更新内存中的策略之后才通知 watcher，此时清空本节点的判定缓存
+func (w *RedisWatcher) publish(msg policyMessage) error {
+	invalidateDecisions()
+
+	ctx := context.Background()
+	version, err := w.client.Incr(ctx, w.versionKey).Result()
+	if err != nil {
+		return err
+	}
+	policyVersion.Store(version)
+
+	msg.Instance = w.instance
+	msg.Version = version
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return w.client.Publish(ctx, w.channel, data).Err()
+}
+
+// SetUpdateCallback 设置收到并应用变更后的回调
+func (w *RedisWatcher) SetUpdateCallback(callback func(string)) error {
+	w.callback = callback
+	return nil
+}
+
+// Update 通知其他节点全量加载策略
+func (w *RedisWatcher) Update() error {
+	return w.publish(policyMessage{Op: opReload})
+}
+
+// Close 停止订阅和定时加载
+func (w *RedisWatcher) Close() {
+	close(w.stop)
+	if w.pubsub != nil {
+		_ = w.pubsub.Close()
+	}
+	w.wg.Wait()
+}
+
+// UpdateForAddPolicy 广播新增的单条策略
+func (w *RedisWatcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.publish(policyMessage{Op: opAddPolicies, Sec: sec, Ptype: ptype, Rules: [][]string{params}})
+}
+
+// UpdateForRemovePolicy 广播删除的单条策略
+func (w *RedisWatcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.publish(policyMessage{Op: opRemovePolicies, Sec: sec, Ptype: ptype, Rules: [][]string{params}})
+}
+
+// UpdateForRemoveFilteredPolicy 广播按条件删除的策略
+func (w *RedisWatcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.publish(policyMessage{Op: opRemoveFiltered, Sec: sec, Ptype: ptype, FieldIndex: fieldIndex, FieldValues: fieldValues})
+}
+
+// UpdateForSavePolicy 整体保存策略后通知其他节点全量加载
+func (w *RedisWatcher) UpdateForSavePolicy(model model.Model) error {
+	return w.Update()
+}
+
+// UpdateForAddPolicies 广播批量新增的策略
+func (w *RedisWatcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(policyMessage{Op: opAddPolicies, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+// UpdateForRemovePolicies 广播批量删除的策略
+func (w *RedisWatcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(policyMessage{Op: opRemovePolicies, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+// reloadPolicy 从数据库全量加载策略，并记录对应的版本号
+func reloadPolicy(version int64) error {
+	if err := Enforcer.LoadPolicy(); err != nil {
+		return err
+	}
+	invalidateDecisions()
+	runRemoteUpdateHook()
+	policyVersion.Store(version)
+	lastReload.Store(time.Now().Unix())
+	slog.Info("策略已全量加载", "version", version)
+	return nil
+}
+
+// ReloadPolicy 从数据库全量加载策略，用于 SIGHUP 等手动触发的重新加载
+func ReloadPolicy() error {
+	version := PolicyVersion()
+	if watcher != nil {
+		version = watcher.currentVersion()
+	}
+	return reloadPolicy(version)
+}
+
+// watcher 当前运行的策略同步器，未启用时为 nil
+var watcher *RedisWatcher
+
+// startWatcher 创建并启动策略同步器，并将本节点版本对齐到 Redis 中的全局版本
+func startWatcher(cfg *config.CasbinConfig) error {
+	w := NewRedisWatcher(database.RDB, cfg.WatcherChannel)
+	if err := Enforcer.SetWatcher(w); err != nil {
+		return err
+	}
+	policyVersion.Store(w.currentVersion())
+	w.Start(cfg.ReloadInterval)
+	watcher = w
+
+	slog.Info("Casbin 策略同步已启用", "channel", cfg.WatcherChannel, "instance", w.instance)
+	return nil
+}
+
+// StopWatcher 停止策略同步器
+func StopWatcher() {
+	if watcher != nil {
+		watcher.Close()
+		watcher = nil
+	}
+}
+
+// PolicyStatus 本节点的策略状态
+type PolicyStatus struct {
+	Instance   string     `json:"instance,omitempty"`
+	Watcher    bool       `json:"watcher"`
+	Version    int64      `json:"version"`
+	Latest     int64      `json:"latest"` // Redis 中的全局版本，与 version 不一致说明本节点落后
+	LastReload time.Time  `json:"last_reload"`
+	Cache      CacheStats `json:"cache"` // 判定缓存统计
+}
+
+// GetPolicyStatus 返回本节点的策略版本等状态
+func GetPolicyStatus() PolicyStatus {
+	status := PolicyStatus{
+		Version:    policyVersion.Load(),
+		LastReload: time.Unix(lastReload.Load(), 0),
+		Cache:      GetCacheStats(),
+	}
+	status.Latest = status.Version
+	if watcher != nil {
+		status.Instance = watcher.instance
+		status.Watcher = true
+		status.Latest = watcher.currentVersion()
+	}
+	return status
+}
@@ -0,0 +1,95 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/service"
+)
+
+var (
+	catalogMu    sync.RWMutex
+	routeCatalog []service.RoutePermission
+)
+
+// protectedGroup 受 CasbinAuth 保护的路由组，注册路由的同时登记权限名称和说明
+type protectedGroup struct {
+	group  *gin.RouterGroup
+	routes []service.RoutePermission
+}
+
+// handle 注册路由并登记权限，权限名称重复视为编程错误
+func (g *protectedGroup) handle(method, relativePath, name, description string, handler gin.HandlerFunc) {
+	for _, route := range g.routes {
+		if route.Name == name {
+			panic(fmt.Sprintf("权限名称重复: %s（%s %s）", name, method, relativePath))
+		}
+	}
+	g.group.Handle(method, relativePath, handler)
+
+	// 与 gin 一致保留末尾的斜杠，否则与实际注册的路由对不上
+	resource := path.Join(g.group.BasePath(), relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(resource, "/") {
+		resource += "/"
+	}
+	g.routes = append(g.routes, service.RoutePermission{
+		Name:        name,
+		Resource:    resource,
+		Action:      method,
+		Description: description,
+	})
+}
+
+// GET 注册受保护的 GET 路由
+func (g *protectedGroup) GET(relativePath, name, description string, handler gin.HandlerFunc) {
+	g.handle(http.MethodGet, relativePath, name, description, handler)
+}
+
+// POST 注册受保护的 POST 路由
+func (g *protectedGroup) POST(relativePath, name, description string, handler gin.HandlerFunc) {
+	g.handle(http.MethodPost, relativePath, name, description, handler)
+}
+
+// PUT 注册受保护的 PUT 路由
+func (g *protectedGroup) PUT(relativePath, name, description string, handler gin.HandlerFunc) {
+	g.handle(http.MethodPut, relativePath, name, description, handler)
+}
+
+// DELETE 注册受保护的 DELETE 路由
+func (g *protectedGroup) DELETE(relativePath, name, description string, handler gin.HandlerFunc) {
+	g.handle(http.MethodDelete, relativePath, name, description, handler)
+}
+
+// publish 将登记的路由作为当前的权限目录
+func (g *protectedGroup) publish() {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	routeCatalog = slices.Clone(g.routes)
+}
+
+// PermissionCatalog 返回 SetupRouter 登记的权限目录，以及 r 中未受权限保护的路由
+func PermissionCatalog(r *gin.Engine) *service.PermissionCatalog {
+	catalogMu.RLock()
+	routes := slices.Clone(routeCatalog)
+	catalogMu.RUnlock()
+
+	protected := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		protected[route.Action+" "+route.Resource] = true
+	}
+
+	catalog := &service.PermissionCatalog{Routes: routes}
+	for _, info := range r.Routes() {
+		key := info.Method + " " + info.Path
+		if !protected[key] {
+			catalog.Unprotected = append(catalog.Unprotected, key)
+		}
+	}
+	slices.Sort(catalog.Unprotected)
+	return catalog
+}
@@ -0,0 +1,30 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/api"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/middleware"
+)
+
+// SetupDebugRouter 单独调试地址上的诊断路由，不做认证，只应监听在本机回环地址
+func SetupDebugRouter(cfg *config.Config) *gin.Engine {
+	r := gin.New()
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Logger())
+	r.Use(middleware.Recovery())
+
+	debugAPI := api.NewDebugAPI(cfg)
+	debug := r.Group("/debug")
+	{
+		debug.GET("/pprof/", debugAPI.Pprof)
+		debug.GET("/pprof/:name", debugAPI.Pprof)
+		debug.POST("/pprof/:name", debugAPI.Pprof)
+		debug.GET("/runtime", debugAPI.Runtime)
+		debug.GET("/config", debugAPI.Config)
+		debug.GET("/loglevel", debugAPI.GetLogLevel)
+		debug.PUT("/loglevel", debugAPI.SetLogLevel)
+	}
+
+	return r
+}
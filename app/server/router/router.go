@@ -1,10 +1,14 @@
 package router
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/api"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/metrics"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // SetupRouter 设置路由
@@ -16,21 +20,44 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 	r := gin.New()
 
 	// 使用中间件
-	r.Use(middleware.Recovery())
+	if cfg.Tracing.Enabled {
+		r.Use(otelgin.Middleware(cfg.Tracing.ServiceName))
+	}
+	r.Use(middleware.RequestID())
 	r.Use(middleware.Logger())
-	r.Use(middleware.CORS())
+	r.Use(middleware.Recovery())
+	if cfg.Metrics.Enabled {
+		r.Use(middleware.Metrics())
+	}
+	r.Use(middleware.CORS(&cfg.CORS))
+	r.Use(middleware.SecurityHeaders(&cfg.Security))
 
 	// API处理器
 	authAPI := api.NewAuthAPI(cfg)
-	userAPI := api.NewUserAPI()
+	userAPI := api.NewUserAPI(cfg)
 	roleAPI := api.NewRoleAPI()
 	permissionAPI := api.NewPermissionAPI()
+	tenantAPI := api.NewTenantAPI()
+	groupAPI := api.NewGroupAPI()
+	authzAPI := api.NewAuthzAPI()
+	policyAPI := api.NewPolicyAPI()
+	roleGrantAPI := api.NewRoleGrantAPI()
+	healthAPI := api.NewHealthAPI()
+	cspReportAPI := api.NewCSPReportAPI()
+
+	// 存活和就绪探针
+	r.GET("/livez", healthAPI.Livez)
+	r.GET("/readyz", healthAPI.Readyz)
 
 	// 公开路由
 	public := r.Group("/api")
 	{
 		public.POST("/auth/register", authAPI.Register)
 		public.POST("/auth/login", authAPI.Login)
+		public.POST("/auth/logout", authAPI.Logout)
+
+		// CSP 违规上报
+		public.POST("/csp-report", cspReportAPI.Report)
 		
 		// 健康检查
 		public.GET("/health", func(c *gin.Context) {
@@ -44,10 +71,21 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 	// 需要认证的路由
 	auth := r.Group("/api")
 	auth.Use(middleware.JWTAuth())
+	auth.Use(middleware.Tenant())
 	{
 		// 用户个人资料
 		auth.GET("/users/profile", authAPI.GetProfile)
 		auth.PUT("/users/profile", authAPI.GetProfile) // TODO: 实现更新资料
+
+		// 当前用户所属租户
+		auth.GET("/tenants/mine", tenantAPI.GetMyTenants)
+
+		// 当前用户申请临时角色
+		auth.POST("/role-grants", roleGrantAPI.RequestGrant)
+		auth.GET("/role-grants/mine", roleGrantAPI.GetMyGrants)
+
+		// 当前用户的批量权限检查
+		auth.POST("/authz/check", authzAPI.Check)
 		
 		// Dashboard
 		auth.GET("/dashboard", func(c *gin.Context) {
@@ -62,33 +100,127 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 		})
 	}
 
+	// 条件策略（p2）所需的资源加载器
+	middleware.RegisterResourceLoader(http.MethodGet, "/api/users/:id", userAPI.LoadUserResource)
+	middleware.RegisterResourceLoader(http.MethodPut, "/api/users/:id", userAPI.LoadUserResource)
+	middleware.RegisterResourceLoader(http.MethodDelete, "/api/users/:id", userAPI.LoadUserResource)
+
 	// 需要认证和权限的路由
 	authz := r.Group("/api")
+	if cfg.Metrics.Enabled && cfg.Metrics.Addr == "" && cfg.Metrics.Token != "" {
+		authz.Use(middleware.MetricsToken(cfg.Metrics.Token, "/api/metrics", metrics.Handler()))
+	}
 	authz.Use(middleware.JWTAuth())
+	authz.Use(middleware.Tenant())
 	authz.Use(middleware.CasbinAuth())
+	protected := &protectedGroup{group: authz}
 	{
 		// 用户管理
-		authz.GET("/users", userAPI.GetUsers)
-		authz.GET("/users/:id", userAPI.GetUserByID)
-		authz.POST("/users", userAPI.CreateUser)
-		authz.PUT("/users/:id", userAPI.UpdateUser)
-		authz.DELETE("/users/:id", userAPI.DeleteUser)
-		authz.POST("/users/:id/roles", userAPI.AssignRole)
+		protected.GET("/users", "users.list", "获取用户列表", userAPI.GetUsers)
+		protected.GET("/users/deleted", "users.list_deleted", "获取已删除用户列表", userAPI.GetDeletedUsers)
+		protected.GET("/users/export", "users.export", "导出用户", userAPI.ExportUsers)
+		protected.POST("/users/import", "users.import", "导入用户", userAPI.ImportUsers)
+		protected.GET("/users/:id", "users.get", "获取用户详情", userAPI.GetUserByID)
+		protected.POST("/users", "users.create", "创建用户", userAPI.CreateUser)
+		protected.PUT("/users/:id", "users.update", "更新用户", userAPI.UpdateUser)
+		protected.DELETE("/users/:id", "users.delete", "删除用户", userAPI.DeleteUser)
+		protected.POST("/users/:id/roles", "users.assign_role", "为用户分配角色", userAPI.AssignRole)
+		protected.GET("/users/:id/effective-roles", "users.effective_roles", "查看用户的有效角色", userAPI.GetEffectiveRoles)
+		protected.POST("/users/:id/disable", "users.disable", "禁用用户", userAPI.DisableUser)
+		protected.POST("/users/:id/enable", "users.enable", "启用用户", userAPI.EnableUser)
+		protected.POST("/users/:id/restore", "users.restore", "恢复已删除用户", userAPI.RestoreUser)
+		protected.DELETE("/users/:id/purge", "users.purge", "彻底清除用户", userAPI.PurgeUser)
 
 		// 角色管理
-		authz.GET("/roles", roleAPI.GetRoles)
-		authz.GET("/roles/:id", roleAPI.GetRoleByID)
-		authz.POST("/roles", roleAPI.CreateRole)
-		authz.PUT("/roles/:id", roleAPI.UpdateRole)
-		authz.DELETE("/roles/:id", roleAPI.DeleteRole)
-		authz.POST("/roles/:id/permissions", roleAPI.AssignPermission)
+		protected.GET("/roles", "roles.list", "获取角色列表", roleAPI.GetRoles)
+		protected.GET("/roles/:id", "roles.get", "获取角色详情", roleAPI.GetRoleByID)
+		protected.POST("/roles", "roles.create", "创建角色", roleAPI.CreateRole)
+		protected.PUT("/roles/:id", "roles.update", "更新角色", roleAPI.UpdateRole)
+		protected.DELETE("/roles/:id", "roles.delete", "删除角色", roleAPI.DeleteRole)
+		protected.POST("/roles/:id/permissions", "roles.assign_permission", "为角色分配权限", roleAPI.AssignPermission)
+		protected.GET("/roles/:id/permissions/resolved", "roles.resolved_permissions", "查看角色的完整权限集合", roleAPI.GetResolvedPermissions)
+		protected.GET("/roles/:id/parents", "roles.list_parents", "获取角色的父角色", roleAPI.GetParents)
+		protected.POST("/roles/:id/parents", "roles.add_parent", "添加父角色", roleAPI.AddParent)
+		protected.DELETE("/roles/:id/parents/:parent_id", "roles.remove_parent", "移除父角色", roleAPI.RemoveParent)
 
 		// 权限管理
-		authz.GET("/permissions", permissionAPI.GetPermissions)
-		authz.GET("/permissions/:id", permissionAPI.GetPermissionByID)
-		authz.POST("/permissions", permissionAPI.CreatePermission)
-		authz.PUT("/permissions/:id", permissionAPI.UpdatePermission)
-		authz.DELETE("/permissions/:id", permissionAPI.DeletePermission)
+		protected.GET("/permissions", "permissions.list", "获取权限列表", permissionAPI.GetPermissions)
+		protected.GET("/permissions/:id", "permissions.get", "获取权限详情", permissionAPI.GetPermissionByID)
+		protected.POST("/permissions", "permissions.create", "创建权限", permissionAPI.CreatePermission)
+		protected.PUT("/permissions/:id", "permissions.update", "更新权限", permissionAPI.UpdatePermission)
+		protected.DELETE("/permissions/:id", "permissions.delete", "删除权限", permissionAPI.DeletePermission)
+
+		// 权限判定解释
+		protected.GET("/authz/explain", "authz.explain", "解释权限判定", authzAPI.Explain)
+		protected.GET("/authz/policy-status", "authz.policy_status", "查看策略同步状态", authzAPI.PolicyStatus)
+
+		// 策略管理
+		protected.GET("/policies", "policies.list", "获取策略列表", policyAPI.GetPolicies)
+		protected.POST("/policies", "policies.create", "添加策略", policyAPI.AddPolicies)
+		protected.PUT("/policies", "policies.update", "修改策略", policyAPI.UpdatePolicy)
+		protected.DELETE("/policies", "policies.delete", "删除策略", policyAPI.RemovePolicies)
+		protected.GET("/policies/roles/:role/members", "policies.list_role_members", "获取角色成员", policyAPI.GetRoleMembers)
+		protected.POST("/policies/roles/:role/members", "policies.add_role_member", "授予用户角色", policyAPI.AddRoleMember)
+		protected.DELETE("/policies/roles/:role/members/:username", "policies.remove_role_member", "撤销用户角色", policyAPI.RemoveRoleMember)
+
+		// 临时角色审批
+		protected.GET("/role-grants", "role_grants.list", "获取临时角色申请列表", roleGrantAPI.GetGrants)
+		protected.POST("/role-grants/:id/approve", "role_grants.approve", "批准临时角色申请", roleGrantAPI.ApproveGrant)
+		protected.POST("/role-grants/:id/reject", "role_grants.reject", "拒绝临时角色申请", roleGrantAPI.RejectGrant)
+		protected.POST("/role-grants/:id/revoke", "role_grants.revoke", "撤销临时角色", roleGrantAPI.RevokeGrant)
+
+		// 用户组管理
+		protected.GET("/groups", "groups.list", "获取用户组列表", groupAPI.GetGroups)
+		protected.GET("/groups/:id", "groups.get", "获取用户组详情", groupAPI.GetGroupByID)
+		protected.POST("/groups", "groups.create", "创建用户组", groupAPI.CreateGroup)
+		protected.PUT("/groups/:id", "groups.update", "更新用户组", groupAPI.UpdateGroup)
+		protected.DELETE("/groups/:id", "groups.delete", "删除用户组", groupAPI.DeleteGroup)
+		protected.PUT("/groups/:id/parent", "groups.set_parent", "设置父组", groupAPI.SetParent)
+		protected.GET("/groups/:id/members", "groups.list_members", "获取用户组成员", groupAPI.GetMembers)
+		protected.POST("/groups/:id/members", "groups.add_members", "添加用户组成员", groupAPI.AddMembers)
+		protected.DELETE("/groups/:id/members/:user_id", "groups.remove_member", "移除用户组成员", groupAPI.RemoveMember)
+		protected.GET("/groups/:id/roles", "groups.list_roles", "获取用户组角色", groupAPI.GetRoles)
+		protected.POST("/groups/:id/roles", "groups.add_role", "为用户组授予角色", groupAPI.AddRole)
+		protected.DELETE("/groups/:id/roles/:role", "groups.remove_role", "撤销用户组角色", groupAPI.RemoveRole)
+
+		// 租户管理
+		protected.GET("/tenants", "tenants.list", "获取租户列表", tenantAPI.GetTenants)
+		protected.GET("/tenants/:id", "tenants.get", "获取租户详情", tenantAPI.GetTenantByID)
+		protected.POST("/tenants", "tenants.create", "创建租户", tenantAPI.CreateTenant)
+		protected.PUT("/tenants/:id", "tenants.update", "更新租户", tenantAPI.UpdateTenant)
+		protected.DELETE("/tenants/:id", "tenants.delete", "删除租户", tenantAPI.DeleteTenant)
+		protected.GET("/tenants/:id/members", "tenants.list_members", "获取租户成员", tenantAPI.GetMembers)
+		protected.POST("/tenants/:id/members", "tenants.add_member", "添加租户成员", tenantAPI.AddMember)
+		protected.DELETE("/tenants/:id/members/:user_id", "tenants.remove_member", "移除租户成员", tenantAPI.RemoveMember)
+
+		// 当前租户成员管理（租户由 X-Tenant 请求头或 Token 决定）
+		protected.GET("/tenant/members", "tenant.list_members", "获取当前租户成员", tenantAPI.GetMembers)
+		protected.POST("/tenant/members", "tenant.add_member", "添加当前租户成员", tenantAPI.AddMember)
+		protected.DELETE("/tenant/members/:user_id", "tenant.remove_member", "移除当前租户成员", tenantAPI.RemoveMember)
+
+		// 诊断接口，配置了单独的调试地址时改由 SetupDebugRouter 提供
+		if cfg.Debug.Enabled && cfg.Debug.Addr == "" {
+			debugAPI := api.NewDebugAPI(cfg)
+			protected.GET("/debug/pprof/", "debug.pprof_index", "查看 pprof 索引", debugAPI.Pprof)
+			protected.GET("/debug/pprof/:name", "debug.pprof", "采集 pprof 性能数据", debugAPI.Pprof)
+			protected.POST("/debug/pprof/:name", "debug.pprof_symbol", "查询 pprof 符号", debugAPI.Pprof)
+			protected.GET("/debug/runtime", "debug.runtime", "查看运行时统计", debugAPI.Runtime)
+			protected.GET("/debug/config", "debug.config", "查看生效配置", debugAPI.Config)
+			protected.GET("/debug/loglevel", "debug.get_log_level", "查看日志级别", debugAPI.GetLogLevel)
+			protected.PUT("/debug/loglevel", "debug.set_log_level", "修改日志级别", debugAPI.SetLogLevel)
+		}
+
+		// 指标含路由流量、连接池和鉴权统计，主端口上同样需要 Casbin 权限，抓取程序可改用 METRICS_TOKEN 静态令牌；
+		// 配置了单独的管理端口时改由该端口提供
+		if cfg.Metrics.Enabled && cfg.Metrics.Addr == "" {
+			protected.GET("/metrics", "metrics.get", "查看 Prometheus 指标", gin.WrapH(metrics.Handler()))
+		}
+	}
+	protected.publish()
+
+	// 前端页面
+	if web := newSPA(cfg.Web.Dir); web != nil {
+		r.NoRoute(web.handle)
 	}
 
 	return r
@@ -0,0 +1,104 @@
+package router
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/middleware"
+)
+
+// noncePlaceholder index.html 中的 nonce 占位符，与 vite.config.ts 的 html.cspNonce 一致
+const noncePlaceholder = "__CSP_NONCE__"
+
+var (
+	// 需要携带 nonce 的标签：script、style 和以 <link> 加载的样式表、模块预加载
+	nonceTagPattern = regexp.MustCompile(`(?i)<(script|style)\b[^>]*>|<link\b[^>]*\brel=["']?(stylesheet|modulepreload)\b[^>]*>`)
+	headPattern     = regexp.MustCompile(`(?i)<head\b[^>]*>`)
+)
+
+// spa 提供前端构建产物：存在的静态文件直接返回，其余路径返回注入了 CSP nonce 的 index.html
+type spa struct {
+	dir   string
+	index []byte
+}
+
+// newSPA 读取前端构建目录，目录中没有 index.html 时返回 nil
+func newSPA(dir string) *spa {
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return nil
+	}
+	return &spa{dir: dir, index: prepareIndex(index)}
+}
+
+// prepareIndex 为未带 nonce 的标签补上占位符，并注入 csp-nonce meta 供前端运行时读取；
+// 使用 vite 的 html.cspNonce 构建时这些已经存在
+func prepareIndex(index []byte) []byte {
+	index = nonceTagPattern.ReplaceAllFunc(index, func(tag []byte) []byte {
+		if bytes.Contains(bytes.ToLower(tag), []byte(" nonce=")) {
+			return tag
+		}
+		end := len(tag) - 1
+		if tag[end-1] == '/' {
+			end--
+		}
+		return append(append(tag[:end:end], ` nonce="`+noncePlaceholder+`"`...), tag[end:]...)
+	})
+	if !bytes.Contains(index, []byte(`property="csp-nonce"`)) {
+		meta := []byte(`<meta property="csp-nonce" nonce="` + noncePlaceholder + `">`)
+		if loc := headPattern.FindIndex(index); loc != nil {
+			index = append(index[:loc[1]:loc[1]], append(meta, index[loc[1]:]...)...)
+		}
+	}
+	return index
+}
+
+// handle 作为 NoRoute 处理器：/api 下的未知路径返回 JSON 404，存在的静态文件直接返回，
+// 其余 GET/HEAD 请求交给前端路由
+func (s *spa) handle(c *gin.Context) {
+	p := c.Request.URL.Path
+	if p == "/api" || strings.HasPrefix(p, "/api/") {
+		notFound(c)
+		return
+	}
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		notFound(c)
+		return
+	}
+
+	name := path.Clean("/" + p)
+	if name != "/" && name != "/index.html" {
+		file := filepath.Join(s.dir, filepath.FromSlash(name))
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			// vite 产物的文件名带内容哈希，可以长期缓存
+			if strings.HasPrefix(name, "/assets/") {
+				c.Header("Cache-Control", "public, max-age=31536000, immutable")
+			}
+			c.File(file)
+			return
+		}
+		// 带扩展名的路径视为缺失的静态资源，不回退到 index.html
+		if path.Ext(name) != "" {
+			notFound(c)
+			return
+		}
+	}
+
+	// index.html 每次携带新的 nonce，不能缓存
+	c.Header("Cache-Control", "no-store")
+	body := bytes.ReplaceAll(s.index, []byte(noncePlaceholder), []byte(middleware.CSPNonce(c)))
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body)
+}
+
+func notFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"code":    404,
+		"message": "资源不存在",
+	})
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/logging"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"gorm.io/gorm"
+)
+
+// AuditActorSystem 后台任务写入审计日志时使用的操作者
+const AuditActorSystem = "system"
+
+// recordAudit 写入一条审计日志，detail 以 JSON 保存
+// 同时记录到 tx 的 context 中的 logger，便于按请求 ID 关联
+func recordAudit(tx *gorm.DB, actor, action, target string, detail any) error {
+	log := model.AuditLog{
+		Actor:  actor,
+		Action: action,
+		Target: target,
+	}
+	if detail != nil {
+		data, err := json.Marshal(detail)
+		if err != nil {
+			return err
+		}
+		log.Detail = string(data)
+	}
+	if err := tx.Create(&log).Error; err != nil {
+		return err
+	}
+
+	ctx := tx.Statement.Context
+	logging.FromContext(ctx).InfoContext(ctx, "审计", "actor", actor, "action", action, "target", target)
+	return nil
+}
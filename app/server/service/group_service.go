@@ -0,0 +1,421 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"gorm.io/gorm"
+)
+
+// maxGroupDepth 用户组最大嵌套层数
+// Casbin 只解析 rbac.MaxRoleDepth 层继承，这一限制作用于 用户 -> 用户组 -> ... -> 角色 -> 父角色 的整条链，
+// 用户到最内层组、最外层组到角色各占一层，因此用户组本身最多嵌套 rbac.MaxRoleDepth-1 层
+const maxGroupDepth = rbac.MaxRoleDepth - 1
+
+var (
+	// ErrGroupCycle 设置父组会形成循环
+	ErrGroupCycle = errors.New("用户组嵌套存在循环")
+	// ErrGroupTooDeep 用户组嵌套加上角色继承的层数过多
+	ErrGroupTooDeep = fmt.Errorf("用户 -> 用户组 -> 角色的继承链不能超过 %d 层", rbac.MaxRoleDepth)
+	// ErrGroupTenantMismatch 用户组与父组或成员不属于同一租户
+	ErrGroupTenantMismatch = errors.New("用户组与目标不属于同一租户")
+)
+
+// GroupService 用户组服务
+// 用户组属于租户数据，ctx 中的当前租户（database.WithTenant）决定可见范围
+type GroupService struct{}
+
+// domainOf 返回用户组所在的 Casbin 域
+func (s *GroupService) domainOf(group *model.Group) (string, error) {
+	if group.TenantID == 0 {
+		return rbac.GlobalDomain, nil
+	}
+	var tenant model.Tenant
+	if err := database.DB.Unscoped().First(&tenant, group.TenantID).Error; err != nil {
+		return "", err
+	}
+	return tenant.Code, nil
+}
+
+// CreateGroup 创建用户组
+func (s *GroupService) CreateGroup(ctx context.Context, group *model.Group) error {
+	parentID := group.ParentID
+	group.ParentID = nil
+
+	if err := database.DB.WithContext(ctx).Create(group).Error; err != nil {
+		return err
+	}
+	if parentID != nil {
+		return s.SetParent(ctx, group.ID, parentID)
+	}
+	return nil
+}
+
+// GetGroupByID 根据ID获取用户组
+func (s *GroupService) GetGroupByID(ctx context.Context, id uint) (*model.Group, error) {
+	var group model.Group
+	if err := database.DB.WithContext(ctx).Preload("Parent").First(&group, id).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetAllGroups 获取所有用户组（分页）
+func (s *GroupService) GetAllGroups(ctx context.Context, page, pageSize int) ([]model.Group, int64, error) {
+	var groups []model.Group
+	var total int64
+
+	offset := (page - 1) * pageSize
+	db := database.DB.WithContext(ctx)
+
+	if err := db.Model(&model.Group{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := db.Offset(offset).Limit(pageSize).Find(&groups).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return groups, total, nil
+}
+
+// UpdateGroup 更新用户组基本信息（父组通过 SetParent 修改）
+func (s *GroupService) UpdateGroup(ctx context.Context, group *model.Group) error {
+	result := database.DB.WithContext(ctx).Model(group).
+		Select("name", "display_name", "description").
+		Updates(group)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteGroup 删除用户组（软删除）
+// 子组会被提升为顶层组，组内成员关系和 Casbin 中以该组为两端的 g 规则一并删除
+func (s *GroupService) DeleteGroup(ctx context.Context, id uint) error {
+	group, err := s.GetGroupByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Group{}).Where("parent_id = ?", id).Update("parent_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(group).Association("Members").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(group).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = rbac.DeleteSubject(rbac.GroupSubject(id))
+	return err
+}
+
+// SetParent 设置或清除父组，parentID 为 nil 时变为顶层组
+func (s *GroupService) SetParent(ctx context.Context, id uint, parentID *uint) error {
+	group, err := s.GetGroupByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	domain, err := s.domainOf(group)
+	if err != nil {
+		return err
+	}
+
+	if parentID != nil {
+		parent, err := s.GetGroupByID(ctx, *parentID)
+		if err != nil {
+			return err
+		}
+		if parent.TenantID != group.TenantID {
+			return ErrGroupTenantMismatch
+		}
+		if err := s.checkCycle(ctx, id, parent); err != nil {
+			return err
+		}
+		below, err := s.depthBelow(ctx, id)
+		if err != nil {
+			return err
+		}
+		above, err := s.depthAbove(ctx, parent, domain)
+		if err != nil {
+			return err
+		}
+		own, err := s.roleReach(id, domain)
+		if err != nil {
+			return err
+		}
+		if below+max(own, above+1) > rbac.MaxRoleDepth {
+			return ErrGroupTooDeep
+		}
+	}
+
+	if err := database.DB.WithContext(ctx).Model(group).Update("parent_id", parentID).Error; err != nil {
+		return err
+	}
+
+	// 同步 Casbin：子组 -> 父组
+	child := rbac.GroupSubject(id)
+	if group.ParentID != nil {
+		if _, err := rbac.RemoveLink(child, rbac.GroupSubject(*group.ParentID), domain); err != nil {
+			return err
+		}
+	}
+	if parentID != nil {
+		if _, err := rbac.AddLink(child, rbac.GroupSubject(*parentID), domain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCycle 沿父组链向上检查，确认把 id 挂到 parent 之下不会形成循环
+func (s *GroupService) checkCycle(ctx context.Context, id uint, parent *model.Group) error {
+	current := parent
+	for depth := 1; ; depth++ {
+		if current.ID == id {
+			return ErrGroupCycle
+		}
+		if depth >= maxGroupDepth {
+			return ErrGroupTooDeep
+		}
+		if current.ParentID == nil {
+			return nil
+		}
+
+		var next model.Group
+		if err := database.DB.WithContext(ctx).First(&next, *current.ParentID).Error; err != nil {
+			return err
+		}
+		current = &next
+	}
+}
+
+// depthBelow 返回从用户到 id 的最长链长：用户 -> 最深的子组 -> ... -> id
+func (s *GroupService) depthBelow(ctx context.Context, id uint) (int, error) {
+	depth := 1
+	level := []uint{id}
+	for depth <= rbac.MaxRoleDepth {
+		var children []uint
+		if err := database.DB.WithContext(ctx).Model(&model.Group{}).Where("parent_id IN ?", level).Pluck("id", &children).Error; err != nil {
+			return 0, err
+		}
+		if len(children) == 0 {
+			break
+		}
+		depth++
+		level = children
+	}
+	return depth, nil
+}
+
+// depthAbove 返回从 group 经父组到最终角色的最长链长：group -> ... -> 父组 -> 角色 -> 父角色
+func (s *GroupService) depthAbove(ctx context.Context, group *model.Group, domain string) (int, error) {
+	depth := 0
+	current := group
+	for hops := 0; hops <= rbac.MaxRoleDepth; hops++ {
+		reach, err := s.roleReach(current.ID, domain)
+		if err != nil {
+			return 0, err
+		}
+		depth = max(depth, hops+reach)
+		if current.ParentID == nil {
+			break
+		}
+
+		var next model.Group
+		if err := database.DB.WithContext(ctx).First(&next, *current.ParentID).Error; err != nil {
+			return 0, err
+		}
+		current = &next
+	}
+	return depth, nil
+}
+
+// roleReach 返回直接授予用户组的角色向上的最长链长：组 -> 角色 -> 父角色，没有角色时为 0
+func (s *GroupService) roleReach(id uint, domain string) (int, error) {
+	reach := 0
+	for _, role := range rbac.GetParents(rbac.GroupSubject(id), domain) {
+		if rbac.IsGroupSubject(role) {
+			continue
+		}
+		depth, err := rbac.RoleDepth(role)
+		if err != nil {
+			return 0, err
+		}
+		reach = max(reach, depth+1)
+	}
+	return reach, nil
+}
+
+// GetMembers 获取用户组的直接成员
+func (s *GroupService) GetMembers(ctx context.Context, id uint) ([]model.User, error) {
+	group, err := s.GetGroupByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []model.User
+	if err := database.DB.Model(group).Association("Members").Find(&members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddMembers 将用户加入用户组
+// 租户用户组只能加入该租户的成员
+func (s *GroupService) AddMembers(ctx context.Context, id uint, userIDs []uint) error {
+	group, err := s.GetGroupByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	domain, err := s.domainOf(group)
+	if err != nil {
+		return err
+	}
+
+	// 请求中可能重复出现同一用户，去重后再与查询结果比较数量
+	userIDs = slices.Compact(slices.Sorted(slices.Values(userIDs)))
+
+	var users []model.User
+	if err := database.DB.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return err
+	}
+	if len(users) != len(userIDs) {
+		return gorm.ErrRecordNotFound
+	}
+
+	if group.TenantID != 0 {
+		var count int64
+		if err := database.DB.Model(&model.TenantMember{}).
+			Where("tenant_id = ? AND user_id IN ?", group.TenantID, userIDs).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(userIDs) {
+			return ErrGroupTenantMismatch
+		}
+	}
+
+	if err := database.DB.Model(group).Association("Members").Append(&users); err != nil {
+		return err
+	}
+
+	subject := rbac.GroupSubject(id)
+	links := make([][]string, 0, len(users))
+	for _, user := range users {
+		links = append(links, []string{user.Username, subject, domain})
+	}
+	_, err = rbac.AddGroupingPolicies(links)
+	return err
+}
+
+// RemoveMember 将用户移出用户组
+func (s *GroupService) RemoveMember(ctx context.Context, id, userID uint) error {
+	group, err := s.GetGroupByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	domain, err := s.domainOf(group)
+	if err != nil {
+		return err
+	}
+
+	var user model.User
+	if err := database.DB.Unscoped().First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	if err := database.DB.Model(group).Association("Members").Delete(&user); err != nil {
+		return err
+	}
+
+	_, err = rbac.RemoveLink(user.Username, rbac.GroupSubject(id), domain)
+	return err
+}
+
+// GetRoles 获取直接授予用户组的角色
+func (s *GroupService) GetRoles(ctx context.Context, id uint) ([]string, error) {
+	group, err := s.GetGroupByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	domain, err := s.domainOf(group)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []string{}
+	for _, parent := range rbac.GetParents(rbac.GroupSubject(id), domain) {
+		if !rbac.IsGroupSubject(parent) {
+			roles = append(roles, parent)
+		}
+	}
+	return roles, nil
+}
+
+// AddRole 为用户组授予角色
+// 角色须在 roles 表中存在；租户用户组与直接授予租户成员一样，须通过 checkGrantableRoles 的校验
+func (s *GroupService) AddRole(ctx context.Context, grantor string, id uint, role string) error {
+	if rbac.IsGroupSubject(role) {
+		return fmt.Errorf("无效的角色: %s", role)
+	}
+	group, err := s.GetGroupByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := database.DB.WithContext(ctx).Where("name = ?", role).First(&model.Role{}).Error; err != nil {
+		return err
+	}
+	domain, err := s.domainOf(group)
+	if err != nil {
+		return err
+	}
+	if group.TenantID != 0 {
+		if err := checkGrantableRoles(ctx, grantor, domain, []string{role}); err != nil {
+			return err
+		}
+	}
+
+	below, err := s.depthBelow(ctx, id)
+	if err != nil {
+		return err
+	}
+	depth, err := rbac.RoleDepth(role)
+	if err != nil {
+		return err
+	}
+	if below+1+depth > rbac.MaxRoleDepth {
+		return ErrGroupTooDeep
+	}
+
+	_, err = rbac.AddLink(rbac.GroupSubject(id), role, domain)
+	return err
+}
+
+// RemoveRole 撤销用户组的角色
+func (s *GroupService) RemoveRole(ctx context.Context, id uint, role string) error {
+	group, err := s.GetGroupByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	domain, err := s.domainOf(group)
+	if err != nil {
+		return err
+	}
+
+	_, err = rbac.RemoveLink(rbac.GroupSubject(id), role, domain)
+	return err
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"gorm.io/gorm"
+)
+
+// RoutePermission 受权限保护的路由及其权限名称
+type RoutePermission struct {
+	Name        string `json:"name"`
+	Resource    string `json:"resource"` // 路由模板，如 /api/users/:id
+	Action      string `json:"action"`
+	Description string `json:"description"`
+}
+
+// PermissionCatalog 从已注册路由中收集的权限目录
+type PermissionCatalog struct {
+	Routes      []RoutePermission `json:"routes"`
+	Unprotected []string          `json:"unprotected"` // 未经权限检查的路由（METHOD /path），包括公开路由和仅需登录的路由
+}
+
+// CatalogReport 权限目录同步结果
+type CatalogReport struct {
+	Created     []string           `json:"created"`
+	Updated     []string           `json:"updated"`
+	Orphaned    []model.Permission `json:"orphaned"` // 资源和操作不对应任何已注册路由的权限
+	Pruned      bool               `json:"pruned"`   // 孤立权限是否已删除
+	Unprotected []string           `json:"unprotected"`
+}
+
+// Print 以文本形式输出同步结果
+func (r *CatalogReport) Print(w io.Writer) error {
+	var b strings.Builder
+	for _, name := range r.Created {
+		fmt.Fprintf(&b, "+ %s\n", name)
+	}
+	for _, name := range r.Updated {
+		fmt.Fprintf(&b, "~ %s\n", name)
+	}
+	for _, permission := range r.Orphaned {
+		mark := "?"
+		if r.Pruned {
+			mark = "-"
+		}
+		fmt.Fprintf(&b, "%s %s（%s %s，无对应路由）\n", mark, permission.Name, permission.Action, permission.Resource)
+	}
+	for _, route := range r.Unprotected {
+		fmt.Fprintf(&b, "! %s（未做权限检查）\n", route)
+	}
+	fmt.Fprintf(&b, "新增 %d，更新 %d，孤立 %d，未保护路由 %d\n",
+		len(r.Created), len(r.Updated), len(r.Orphaned), len(r.Unprotected))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// SyncCatalog 将权限目录同步到 permissions 表
+// 已有权限按资源和操作匹配（其次按名称），名称或说明不一致时更新；不存在的新增。
+// 不对应任何路由的权限只标记为孤立，prune 为 true 时才删除；apply 为 false 时只计算差异不写库。
+// 已软删除的权限不参与匹配，名称唯一索引只约束未删除的记录，因此目录中的权限会被重新创建。
+func (s *PermissionService) SyncCatalog(ctx context.Context, catalog *PermissionCatalog, apply, prune bool) (*CatalogReport, error) {
+	report := &CatalogReport{
+		Created:     []string{},
+		Updated:     []string{},
+		Orphaned:    []model.Permission{},
+		Unprotected: catalog.Unprotected,
+	}
+	if report.Unprotected == nil {
+		report.Unprotected = []string{}
+	}
+
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []model.Permission
+		if err := tx.Find(&existing).Error; err != nil {
+			return err
+		}
+		byRoute := make(map[string]*model.Permission, len(existing))
+		byName := make(map[string]*model.Permission, len(existing))
+		for i := range existing {
+			byRoute[existing[i].Action+" "+existing[i].Resource] = &existing[i]
+			byName[existing[i].Name] = &existing[i]
+		}
+
+		matched := make(map[uint]bool)
+		for _, route := range catalog.Routes {
+			permission := byRoute[route.Action+" "+route.Resource]
+			if permission == nil {
+				permission = byName[route.Name]
+			}
+
+			if permission == nil {
+				report.Created = append(report.Created, route.Name)
+				if apply {
+					err := tx.Create(&model.Permission{
+						Name:        route.Name,
+						DisplayName: route.Description,
+						Description: route.Description,
+						Resource:    route.Resource,
+						Action:      route.Action,
+					}).Error
+					if err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			matched[permission.ID] = true
+			if permission.Name == route.Name && permission.Resource == route.Resource &&
+				permission.Action == route.Action && permission.Description == route.Description {
+				continue
+			}
+			report.Updated = append(report.Updated, route.Name)
+			if apply {
+				err := tx.Model(permission).Updates(map[string]any{
+					"name":        route.Name,
+					"description": route.Description,
+					"resource":    route.Resource,
+					"action":      route.Action,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, permission := range existing {
+			if !matched[permission.ID] {
+				report.Orphaned = append(report.Orphaned, permission)
+			}
+		}
+		if apply && prune && len(report.Orphaned) > 0 {
+			ids := make([]uint, 0, len(report.Orphaned))
+			for _, permission := range report.Orphaned {
+				ids = append(ids, permission.ID)
+			}
+			if err := tx.Exec("DELETE FROM role_permissions WHERE permission_id IN ?", ids).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&model.Permission{}, ids).Error; err != nil {
+				return err
+			}
+			report.Pruned = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
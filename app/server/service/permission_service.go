@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
 )
@@ -9,14 +11,14 @@ import (
 type PermissionService struct{}
 
 // CreatePermission 创建权限
-func (s *PermissionService) CreatePermission(permission *model.Permission) error {
-	return database.DB.Create(permission).Error
+func (s *PermissionService) CreatePermission(ctx context.Context, permission *model.Permission) error {
+	return database.DB.WithContext(ctx).Create(permission).Error
 }
 
 // GetPermissionByID 根据ID获取权限
-func (s *PermissionService) GetPermissionByID(id uint) (*model.Permission, error) {
+func (s *PermissionService) GetPermissionByID(ctx context.Context, id uint) (*model.Permission, error) {
 	var permission model.Permission
-	err := database.DB.First(&permission, id).Error
+	err := database.DB.WithContext(ctx).First(&permission, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -24,9 +26,9 @@ func (s *PermissionService) GetPermissionByID(id uint) (*model.Permission, error
 }
 
 // GetPermissionByName 根据名称获取权限
-func (s *PermissionService) GetPermissionByName(name string) (*model.Permission, error) {
+func (s *PermissionService) GetPermissionByName(ctx context.Context, name string) (*model.Permission, error) {
 	var permission model.Permission
-	err := database.DB.Where("name = ?", name).First(&permission).Error
+	err := database.DB.WithContext(ctx).Where("name = ?", name).First(&permission).Error
 	if err != nil {
 		return nil, err
 	}
@@ -34,18 +36,18 @@ func (s *PermissionService) GetPermissionByName(name string) (*model.Permission,
 }
 
 // GetAllPermissions 获取所有权限（分页）
-func (s *PermissionService) GetAllPermissions(page, pageSize int) ([]model.Permission, int64, error) {
+func (s *PermissionService) GetAllPermissions(ctx context.Context, page, pageSize int) ([]model.Permission, int64, error) {
 	var permissions []model.Permission
 	var total int64
 
 	offset := (page - 1) * pageSize
 
-	err := database.DB.Model(&model.Permission{}).Count(&total).Error
+	err := database.DB.WithContext(ctx).Model(&model.Permission{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
-	err = database.DB.Offset(offset).Limit(pageSize).Find(&permissions).Error
+	err = database.DB.WithContext(ctx).Offset(offset).Limit(pageSize).Find(&permissions).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -54,12 +56,12 @@ func (s *PermissionService) GetAllPermissions(page, pageSize int) ([]model.Permi
 }
 
 // UpdatePermission 更新权限
-func (s *PermissionService) UpdatePermission(permission *model.Permission) error {
-	return database.DB.Save(permission).Error
+func (s *PermissionService) UpdatePermission(ctx context.Context, permission *model.Permission) error {
+	return database.DB.WithContext(ctx).Save(permission).Error
 }
 
 // DeletePermission 删除权限（软删除）
-func (s *PermissionService) DeletePermission(id uint) error {
-	return database.DB.Delete(&model.Permission{}, id).Error
+func (s *PermissionService) DeletePermission(ctx context.Context, id uint) error {
+	return database.DB.WithContext(ctx).Delete(&model.Permission{}, id).Error
 }
 
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+)
+
+var (
+	// ErrInvalidPolicy 策略字段不完整
+	ErrInvalidPolicy = errors.New("策略的 sub、obj、act 不能为空")
+	// ErrInvalidEffect 策略的 eft 不合法
+	ErrInvalidEffect = errors.New("策略的 eft 只能为 allow 或 deny")
+	// ErrPolicyNotFound 策略不存在
+	ErrPolicyNotFound = errors.New("策略不存在")
+)
+
+// PolicyService 策略管理服务
+type PolicyService struct{}
+
+// PolicyImportResult 策略导入结果
+type PolicyImportResult struct {
+	Policies    int `json:"policies"`    // 新增的 p 规则数
+	Conditional int `json:"conditional"` // 新增的 p2 规则数
+	Groupings   int `json:"groupings"`   // 新增的 g 规则数
+}
+
+// validatePolicies 校验策略字段
+func validatePolicies(policies []rbac.Policy) error {
+	for _, policy := range policies {
+		if policy.Subject == "" || policy.Object == "" || policy.Action == "" {
+			return ErrInvalidPolicy
+		}
+		if policy.Effect != "" && !rbac.ValidEft(policy.Effect) {
+			return ErrInvalidEffect
+		}
+	}
+	return nil
+}
+
+// GetPolicies 按条件查询策略（分页）
+func (s *PolicyService) GetPolicies(filter rbac.Policy, page, pageSize int) ([]rbac.Policy, int64, error) {
+	policies, err := rbac.ListPolicies(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := int64(len(policies))
+	start := min(max((page-1)*pageSize, 0), len(policies))
+	end := min(start+max(pageSize, 0), len(policies))
+	return policies[start:end], total, nil
+}
+
+// AddPolicies 批量添加策略，返回实际新增的条数
+func (s *PolicyService) AddPolicies(policies []rbac.Policy) (int, error) {
+	if err := validatePolicies(policies); err != nil {
+		return 0, err
+	}
+	rules, conditional := rbac.SplitPolicies(policies)
+	added, err := rbac.AddPolicies(rules)
+	if err != nil {
+		return added, err
+	}
+	n, err := rbac.AddConditionalPolicies(conditional)
+	return added + n, err
+}
+
+// UpdatePolicy 修改一条策略
+func (s *PolicyService) UpdatePolicy(oldPolicy, newPolicy rbac.Policy) error {
+	if err := validatePolicies([]rbac.Policy{oldPolicy, newPolicy}); err != nil {
+		return err
+	}
+	ok, err := rbac.UpdatePolicy(oldPolicy, newPolicy)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
+
+// RemovePolicies 批量删除策略，返回实际删除的条数
+func (s *PolicyService) RemovePolicies(policies []rbac.Policy) (int, error) {
+	if err := validatePolicies(policies); err != nil {
+		return 0, err
+	}
+	rules, conditional := rbac.SplitPolicies(policies)
+	removed, err := rbac.RemovePolicies(rules)
+	if err != nil {
+		return removed, err
+	}
+	n, err := rbac.RemoveConditionalPolicies(conditional)
+	return removed + n, err
+}
+
+// GetRoleMembers 获取在指定域中被直接授予角色的主体
+func (s *PolicyService) GetRoleMembers(role, domain string) []string {
+	return rbac.GetMembersForRole(role, domain)
+}
+
+// AddRoleMember 为用户授予角色
+func (s *PolicyService) AddRoleMember(ctx context.Context, role, username, domain string) error {
+	if err := database.DB.WithContext(ctx).Where("username = ?", username).First(&model.User{}).Error; err != nil {
+		return err
+	}
+
+	var err error
+	if domain == rbac.GlobalDomain {
+		_, err = rbac.AddRoleForUser(username, role)
+	} else {
+		_, err = rbac.AddRoleForUserInDomain(username, role, domain)
+	}
+	return err
+}
+
+// RemoveRoleMember 撤销用户的角色
+func (s *PolicyService) RemoveRoleMember(role, username, domain string) error {
+	var ok bool
+	var err error
+	if domain == rbac.GlobalDomain {
+		ok, err = rbac.DeleteRoleForUser(username, role)
+	} else {
+		ok, err = rbac.DeleteRoleForUserInDomain(username, role, domain)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
+
+// ImportPolicies 导入策略文件中的 p、p2 规则和 g 规则，已存在的规则会被跳过
+func (s *PolicyService) ImportPolicies(set *rbac.PolicySet) (*PolicyImportResult, error) {
+	var err error
+	result := &PolicyImportResult{}
+	if result.Policies, err = rbac.AddPolicies(set.Policies); err != nil {
+		return nil, err
+	}
+	if result.Conditional, err = rbac.AddConditionalPolicies(set.Conditional); err != nil {
+		return result, err
+	}
+	if result.Groupings, err = rbac.AddGroupingPolicies(set.Groupings); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// ExportPolicies 以 CSV 格式导出全部规则
+func (s *PolicyService) ExportPolicies(w io.Writer) error {
+	return rbac.WritePolicies(w)
+}
@@ -0,0 +1,445 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/logging"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidGrantWindow 有效期无效
+	ErrInvalidGrantWindow = errors.New("有效期无效：到期时间必须晚于当前时间和生效时间")
+	// ErrGrantConflict 用户已拥有该角色或已有相同的授权申请
+	ErrGrantConflict = errors.New("用户已拥有该角色或已有相同的授权申请")
+	// ErrGrantStatus 授权当前状态不允许该操作
+	ErrGrantStatus = errors.New("授权当前状态不允许该操作")
+	// ErrSelfApproval 不能审批自己提交的申请
+	ErrSelfApproval = errors.New("不能审批自己提交的申请")
+)
+
+// 限时授权相关的审计动作
+const (
+	auditGrantRequest   = "role_grant.request"
+	auditGrantApprove   = "role_grant.approve"
+	auditGrantReject    = "role_grant.reject"
+	auditGrantRevoke    = "role_grant.revoke"
+	auditGrantExpire    = "role_grant.expire"
+	auditUserRoleExpire = "user_role.expire"
+)
+
+// RoleGrantService 限时角色授权服务
+type RoleGrantService struct{}
+
+// validateGrantWindow 校验有效期：到期时间须晚于当前时间和生效时间
+func validateGrantWindow(notBefore, expiresAt *time.Time) error {
+	if expiresAt == nil {
+		return nil
+	}
+	if !expiresAt.After(time.Now()) || (notBefore != nil && !expiresAt.After(*notBefore)) {
+		return ErrInvalidGrantWindow
+	}
+	return nil
+}
+
+// grantTarget 审计日志中的授权对象
+func grantTarget(grant *model.RoleGrant) string {
+	return fmt.Sprintf("%s -> %s@%s", grant.Username, grant.Role, grant.Domain)
+}
+
+// RequestGrant 提交限时授权申请
+func (s *RoleGrantService) RequestGrant(ctx context.Context, requester string, grant *model.RoleGrant) error {
+	if grant.Domain == "" {
+		grant.Domain = rbac.GlobalDomain
+	}
+	if err := validateGrantWindow(grant.NotBefore, &grant.ExpiresAt); err != nil {
+		return err
+	}
+	db := database.DB.WithContext(ctx)
+	if err := db.Where("username = ?", grant.Username).First(&model.User{}).Error; err != nil {
+		return err
+	}
+
+	var count int64
+	err := db.Model(&model.RoleGrant{}).
+		Where("username = ? AND role = ? AND domain = ?", grant.Username, grant.Role, grant.Domain).
+		Where("status IN ?", []string{model.RoleGrantPending, model.RoleGrantApproved}).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrGrantConflict
+	}
+
+	grant.ID = 0
+	grant.Status = model.RoleGrantPending
+	grant.RequestedBy = requester
+	grant.ReviewedBy = ""
+	grant.ReviewedAt = nil
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(grant).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, requester, auditGrantRequest, grantTarget(grant), grant)
+	})
+}
+
+// ApproveGrant 批准限时授权：写入 g 规则，并在有效期内生效
+func (s *RoleGrantService) ApproveGrant(ctx context.Context, id uint, reviewer string) (*model.RoleGrant, error) {
+	var grant model.RoleGrant
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&grant, id).Error; err != nil {
+			return err
+		}
+		if grant.Status != model.RoleGrantPending {
+			return ErrGrantStatus
+		}
+		if grant.RequestedBy == reviewer {
+			return ErrSelfApproval
+		}
+		if err := validateGrantWindow(grant.NotBefore, &grant.ExpiresAt); err != nil {
+			return err
+		}
+
+		// 已有永久授权时拒绝，避免到期清理时误删
+		granted, err := rbac.HasLink(grant.Username, grant.Role, grant.Domain)
+		if err != nil {
+			return err
+		}
+		if granted {
+			return ErrGrantConflict
+		}
+
+		now := time.Now()
+		grant.Status = model.RoleGrantApproved
+		grant.ReviewedBy = reviewer
+		grant.ReviewedAt = &now
+		if err := tx.Save(&grant).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, reviewer, auditGrantApprove, grantTarget(&grant), grant)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务提交后再写入 g 规则，避免提交失败时留下数据库中并未批准的授权；
+	// 先登记有效期，使规则一出现就受有效期约束
+	rbac.SetGrantWindow(timedGrant(&grant))
+	if _, err := rbac.AddGroupingPolicies([][]string{{grant.Username, grant.Role, grant.Domain}}); err != nil {
+		rbac.ClearGrantWindow(grant.Username, grant.Role, grant.Domain)
+		restoreGrantStatus(ctx, &grant, model.RoleGrantPending, map[string]any{"reviewed_by": "", "reviewed_at": nil})
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// RejectGrant 拒绝限时授权申请
+func (s *RoleGrantService) RejectGrant(ctx context.Context, id uint, reviewer string) (*model.RoleGrant, error) {
+	var grant model.RoleGrant
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&grant, id).Error; err != nil {
+			return err
+		}
+		if grant.Status != model.RoleGrantPending {
+			return ErrGrantStatus
+		}
+
+		now := time.Now()
+		grant.Status = model.RoleGrantRejected
+		grant.ReviewedBy = reviewer
+		grant.ReviewedAt = &now
+		if err := tx.Save(&grant).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, reviewer, auditGrantReject, grantTarget(&grant), grant)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// RevokeGrant 提前撤销已批准的限时授权
+func (s *RoleGrantService) RevokeGrant(ctx context.Context, id uint, actor string) (*model.RoleGrant, error) {
+	var grant model.RoleGrant
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&grant, id).Error; err != nil {
+			return err
+		}
+		if grant.Status != model.RoleGrantApproved {
+			return ErrGrantStatus
+		}
+
+		grant.Status = model.RoleGrantRevoked
+		if err := tx.Save(&grant).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, auditGrantRevoke, grantTarget(&grant), grant)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务提交后再删除 g 规则，避免提交失败时删掉数据库中仍为已批准的授权
+	if err := removeGrantRule(&grant); err != nil {
+		restoreGrantStatus(ctx, &grant, model.RoleGrantApproved, nil)
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// restoreGrantStatus 写入 Casbin 失败后把授权恢复为原状态，使数据库与 g 规则保持一致
+// 恢复同样失败时只能记录错误，需要人工核对该授权
+func restoreGrantStatus(ctx context.Context, grant *model.RoleGrant, status string, updates map[string]any) {
+	if updates == nil {
+		updates = make(map[string]any)
+	}
+	updates["status"] = status
+	err := database.DB.WithContext(ctx).Model(&model.RoleGrant{}).
+		Where("id = ? AND status = ?", grant.ID, grant.Status).
+		Updates(updates).Error
+	if err != nil {
+		logging.FromContext(ctx).Error("恢复限时授权状态失败，数据库与 Casbin 规则不一致",
+			"grant_id", grant.ID, "status", grant.Status, "want", status, "error", err)
+	}
+}
+
+// ListGrants 分页获取限时授权，可按状态和用户过滤
+func (s *RoleGrantService) ListGrants(ctx context.Context, status, username string, page, pageSize int) ([]model.RoleGrant, int64, error) {
+	var grants []model.RoleGrant
+	var total int64
+
+	query := database.DB.WithContext(ctx).Model(&model.RoleGrant{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if username != "" {
+		query = query.Where("username = ?", username)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&grants).Error; err != nil {
+		return nil, 0, err
+	}
+	return grants, total, nil
+}
+
+// LoadGrantWindows 从数据库加载已批准授权和带有效期的 user_roles 关联的有效期
+func (s *RoleGrantService) LoadGrantWindows() error {
+	var grants []model.RoleGrant
+	if err := database.DB.Where("status = ?", model.RoleGrantApproved).Find(&grants).Error; err != nil {
+		return err
+	}
+	timed, err := timedUserRoles(database.DB)
+	if err != nil {
+		return err
+	}
+
+	for i := range grants {
+		timed = append(timed, timedGrant(&grants[i]))
+	}
+	rbac.ReplaceGrantWindows(timed)
+	return nil
+}
+
+// Sweep 清理到期的限时授权和 user_roles 关联，并写入审计日志，返回清理的条数
+// 多个副本同时清理时，以状态更新是否成功决定由哪个副本删除 g 规则
+func (s *RoleGrantService) Sweep(ctx context.Context) (int, error) {
+	now := time.Now()
+	swept := 0
+	db := database.DB.WithContext(ctx)
+
+	var expired []model.RoleGrant
+	err := db.Where("status = ? AND expires_at <= ?", model.RoleGrantApproved, now).Find(&expired).Error
+	if err != nil {
+		return 0, err
+	}
+	for i := range expired {
+		grant := &expired[i]
+		claimed := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&model.RoleGrant{}).
+				Where("id = ? AND status = ?", grant.ID, model.RoleGrantApproved).
+				Update("status", model.RoleGrantExpired)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			grant.Status = model.RoleGrantExpired
+			claimed = true
+			return recordAudit(tx, AuditActorSystem, auditGrantExpire, grantTarget(grant), grant)
+		})
+		if err != nil {
+			return swept, err
+		}
+		if !claimed {
+			continue
+		}
+
+		// 与撤销相同，事务提交后再删除 g 规则，删除失败时恢复为已批准，由下一轮清理重试
+		if err := removeGrantRule(grant); err != nil {
+			restoreGrantStatus(ctx, grant, model.RoleGrantApproved, nil)
+			return swept, err
+		}
+		swept++
+	}
+
+	var userRoles []model.UserRole
+	if err := db.Where("expires_at <= ?", now).Find(&userRoles).Error; err != nil {
+		return swept, err
+	}
+	for i := range userRoles {
+		userRole := &userRoles[i]
+		claimed := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("user_id = ? AND role_id = ?", userRole.UserID, userRole.RoleID).Delete(&model.UserRole{})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			claimed = true
+			return recordAudit(tx, AuditActorSystem, auditUserRoleExpire,
+				fmt.Sprintf("user:%d -> role:%d", userRole.UserID, userRole.RoleID), userRole)
+		})
+		if err != nil {
+			return swept, err
+		}
+		if !claimed {
+			continue
+		}
+
+		// 关联删除提交后再删除对应的 g 规则，失败时恢复关联，由下一轮清理重试
+		if err := removeUserRoleRule(db, userRole); err != nil {
+			if err := db.Create(userRole).Error; err != nil {
+				logging.FromContext(ctx).Error("恢复用户角色关联失败，数据库与 Casbin 规则不一致",
+					"user_id", userRole.UserID, "role_id", userRole.RoleID, "error", err)
+			}
+			return swept, err
+		}
+		swept++
+	}
+
+	return swept, s.LoadGrantWindows()
+}
+
+// removeGrantRule 删除授权对应的 g 规则并清除有效期
+func removeGrantRule(grant *model.RoleGrant) error {
+	if _, err := rbac.RemoveGroupingPolicies([][]string{{grant.Username, grant.Role, grant.Domain}}); err != nil {
+		return err
+	}
+	rbac.ClearGrantWindow(grant.Username, grant.Role, grant.Domain)
+	return nil
+}
+
+// removeUserRoleRule 删除 user_roles 关联对应的 g 规则并清除有效期
+func removeUserRoleRule(db *gorm.DB, userRole *model.UserRole) error {
+	link, err := userRoleLink(db, userRole)
+	if err != nil || link == nil {
+		return err
+	}
+	if _, err := rbac.RemoveGroupingPolicies([][]string{link}); err != nil {
+		return err
+	}
+	rbac.ClearGrantWindow(link[0], link[1], link[2])
+	return nil
+}
+
+// timedGrant 将授权转换为带有效期的 g 规则
+func timedGrant(grant *model.RoleGrant) rbac.TimedGrant {
+	return rbac.TimedGrant{
+		User:   grant.Username,
+		Role:   grant.Role,
+		Domain: grant.Domain,
+		GrantWindow: rbac.GrantWindow{
+			NotBefore: grant.NotBefore,
+			ExpiresAt: grant.ExpiresAt,
+		},
+	}
+}
+
+var (
+	sweeperMu     sync.Mutex
+	sweeperCancel context.CancelFunc
+	sweeperDone   chan struct{}
+)
+
+// StartGrantSweeper 加载限时授权的有效期，并启动定时清理到期授权的后台任务；ctx 结束时任务退出
+func StartGrantSweeper(ctx context.Context, interval time.Duration) error {
+	grantService := &RoleGrantService{}
+	// 其他副本批准、撤销或清理授权时只广播 g 规则，有效期需要在收到变更后从数据库重新加载
+	rbac.OnRemoteUpdate(func() {
+		if err := grantService.LoadGrantWindows(); err != nil {
+			slog.Error("加载限时授权有效期失败", "error", err)
+		}
+	})
+	if err := grantService.LoadGrantWindows(); err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return nil
+	}
+
+	sweeperMu.Lock()
+	defer sweeperMu.Unlock()
+	if sweeperCancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	sweeperCancel = cancel
+	sweeperDone = make(chan struct{})
+
+	// 清理任务不属于任何请求，日志和 SQL 以 component 区分
+	logger := slog.Default().With("component", "grant_sweeper")
+	sweepCtx := logging.NewContext(ctx, logger)
+
+	go func(done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			swept, err := grantService.Sweep(sweepCtx)
+			if err != nil && ctx.Err() == nil {
+				logger.Error("清理到期临时角色失败", "error", err)
+			} else if swept > 0 {
+				logger.Info("已清理到期临时角色", "count", swept)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}(sweeperDone)
+
+	logger.Info("临时角色清理任务已启动", "interval", interval)
+	return nil
+}
+
+// StopGrantSweeper 停止清理任务
+func StopGrantSweeper() {
+	sweeperMu.Lock()
+	defer sweeperMu.Unlock()
+	if sweeperCancel == nil {
+		return
+	}
+	sweeperCancel()
+	<-sweeperDone
+	sweeperCancel = nil
+	sweeperDone = nil
+}
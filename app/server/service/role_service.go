@@ -1,22 +1,51 @@
 package service
 
 import (
+	"context"
+	"errors"
+
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidRoleScope 角色的授予范围无效
+	ErrInvalidRoleScope = errors.New("角色范围只能为 global 或 tenant，admin 只能为 global")
+	// ErrRoleRename 角色名称不能修改
+	ErrRoleRename = errors.New("角色名称创建后不能修改")
 )
 
 // RoleService 角色服务
 type RoleService struct{}
 
-// CreateRole 创建角色
-func (s *RoleService) CreateRole(role *model.Role) error {
-	return database.DB.Create(role).Error
+// CreateRole 创建角色，未指定范围时为全局角色
+func (s *RoleService) CreateRole(ctx context.Context, role *model.Role) error {
+	if err := validateRoleScope(role); err != nil {
+		return err
+	}
+	return database.DB.WithContext(ctx).Create(role).Error
+}
+
+// validateRoleScope 校验角色的授予范围，为空时使用 global
+func validateRoleScope(role *model.Role) error {
+	if role.Scope == "" {
+		role.Scope = model.RoleScopeGlobal
+	}
+	switch {
+	case role.Scope != model.RoleScopeGlobal && role.Scope != model.RoleScopeTenant:
+		return ErrInvalidRoleScope
+	case role.Name == "admin" && role.Scope != model.RoleScopeGlobal:
+		return ErrInvalidRoleScope
+	}
+	return nil
 }
 
 // GetRoleByID 根据ID获取角色
-func (s *RoleService) GetRoleByID(id uint) (*model.Role, error) {
+func (s *RoleService) GetRoleByID(ctx context.Context, id uint) (*model.Role, error) {
 	var role model.Role
-	err := database.DB.Preload("Permissions").First(&role, id).Error
+	err := database.DB.WithContext(ctx).Preload("Permissions").First(&role, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -24,9 +53,9 @@ func (s *RoleService) GetRoleByID(id uint) (*model.Role, error) {
 }
 
 // GetRoleByName 根据名称获取角色
-func (s *RoleService) GetRoleByName(name string) (*model.Role, error) {
+func (s *RoleService) GetRoleByName(ctx context.Context, name string) (*model.Role, error) {
 	var role model.Role
-	err := database.DB.Preload("Permissions").Where("name = ?", name).First(&role).Error
+	err := database.DB.WithContext(ctx).Preload("Permissions").Where("name = ?", name).First(&role).Error
 	if err != nil {
 		return nil, err
 	}
@@ -34,18 +63,18 @@ func (s *RoleService) GetRoleByName(name string) (*model.Role, error) {
 }
 
 // GetAllRoles 获取所有角色（分页）
-func (s *RoleService) GetAllRoles(page, pageSize int) ([]model.Role, int64, error) {
+func (s *RoleService) GetAllRoles(ctx context.Context, page, pageSize int) ([]model.Role, int64, error) {
 	var roles []model.Role
 	var total int64
 
 	offset := (page - 1) * pageSize
 
-	err := database.DB.Model(&model.Role{}).Count(&total).Error
+	err := database.DB.WithContext(ctx).Model(&model.Role{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
-	err = database.DB.Preload("Permissions").Offset(offset).Limit(pageSize).Find(&roles).Error
+	err = database.DB.WithContext(ctx).Preload("Permissions").Offset(offset).Limit(pageSize).Find(&roles).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -54,51 +83,254 @@ func (s *RoleService) GetAllRoles(page, pageSize int) ([]model.Role, int64, erro
 }
 
 // UpdateRole 更新角色
-func (s *RoleService) UpdateRole(role *model.Role) error {
-	return database.DB.Save(role).Error
+// Casbin 的策略、角色分配和继承关系都以名称引用角色，改名会使它们失效，因此名称不能修改；
+// 父角色、权限和用户关联须通过各自的接口维护，这里不会写入
+func (s *RoleService) UpdateRole(ctx context.Context, role *model.Role) error {
+	var old model.Role
+	if err := database.DB.WithContext(ctx).First(&old, role.ID).Error; err != nil {
+		return err
+	}
+	if role.Name == "" {
+		role.Name = old.Name
+	}
+	if role.Name != old.Name {
+		return ErrRoleRename
+	}
+	if role.Scope == "" {
+		role.Scope = old.Scope
+	}
+	if err := validateRoleScope(role); err != nil {
+		return err
+	}
+	return database.DB.WithContext(ctx).Omit("Parents", "Permissions", "Users").Save(role).Error
 }
 
 // DeleteRole 删除角色（软删除）
-func (s *RoleService) DeleteRole(id uint) error {
-	return database.DB.Delete(&model.Role{}, id).Error
+// 角色的继承关系（作为父角色或子角色）一并删除
+func (s *RoleService) DeleteRole(ctx context.Context, id uint) error {
+	var role model.Role
+	if err := database.DB.WithContext(ctx).First(&role, id).Error; err != nil {
+		return err
+	}
+	links, err := s.hierarchyLinks(ctx, id, role.Name)
+	if err != nil {
+		return err
+	}
+
+	err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM role_parents WHERE role_id = ? OR parent_id = ?", id, id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&role).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = rbac.RemoveGroupingPolicies(links)
+	return err
+}
+
+// hierarchyLinks 返回角色的全部继承关系（作为父角色或子角色）对应的 g 规则
+func (s *RoleService) hierarchyLinks(ctx context.Context, id uint, name string) ([][]string, error) {
+	var parents, children []model.Role
+	if err := database.DB.WithContext(ctx).Joins("JOIN role_parents ON role_parents.parent_id = roles.id").
+		Where("role_parents.role_id = ?", id).Find(&parents).Error; err != nil {
+		return nil, err
+	}
+	if err := database.DB.WithContext(ctx).Joins("JOIN role_parents ON role_parents.role_id = roles.id").
+		Where("role_parents.parent_id = ?", id).Find(&children).Error; err != nil {
+		return nil, err
+	}
+
+	links := make([][]string, 0, len(parents)+len(children))
+	for _, parent := range parents {
+		links = append(links, []string{name, parent.Name, rbac.GlobalDomain})
+	}
+	for _, child := range children {
+		links = append(links, []string{child.Name, name, rbac.GlobalDomain})
+	}
+	return links, nil
+}
+
+// GetParents 获取角色直接继承的父角色
+func (s *RoleService) GetParents(ctx context.Context, id uint) ([]model.Role, error) {
+	var role model.Role
+	if err := database.DB.WithContext(ctx).Preload("Parents").First(&role, id).Error; err != nil {
+		return nil, err
+	}
+	return role.Parents, nil
+}
+
+// AddParent 让角色继承父角色的全部权限，并写入 Casbin g 规则（子角色, 父角色, *）
+func (s *RoleService) AddParent(ctx context.Context, id, parentID uint) error {
+	var role, parent model.Role
+	if err := database.DB.WithContext(ctx).First(&role, id).Error; err != nil {
+		return err
+	}
+	if err := database.DB.WithContext(ctx).First(&parent, parentID).Error; err != nil {
+		return err
+	}
+	if err := rbac.CheckInheritance(role.Name, parent.Name); err != nil {
+		return err
+	}
+
+	if err := database.DB.WithContext(ctx).Model(&role).Association("Parents").Append(&parent); err != nil {
+		return err
+	}
+	_, err := rbac.AddLink(role.Name, parent.Name, rbac.GlobalDomain)
+	return err
+}
+
+// RemoveParent 取消角色对父角色的继承
+func (s *RoleService) RemoveParent(ctx context.Context, id, parentID uint) error {
+	var role, parent model.Role
+	if err := database.DB.WithContext(ctx).First(&role, id).Error; err != nil {
+		return err
+	}
+	if err := database.DB.WithContext(ctx).First(&parent, parentID).Error; err != nil {
+		return err
+	}
+
+	if err := database.DB.WithContext(ctx).Model(&role).Association("Parents").Delete(&parent); err != nil {
+		return err
+	}
+	_, err := rbac.RemoveLink(role.Name, parent.Name, rbac.GlobalDomain)
+	return err
+}
+
+// SyncHierarchy 将 role_parents 中的继承关系补写到 Casbin，返回补写的条数
+// 只补不删：Casbin 中角色之间的其他 g 规则可能来自策略文件
+func (s *RoleService) SyncHierarchy(ctx context.Context) (int, error) {
+	var roles []model.Role
+	if err := database.DB.WithContext(ctx).Preload("Parents").Find(&roles).Error; err != nil {
+		return 0, err
+	}
+
+	var links [][]string
+	for _, role := range roles {
+		for _, parent := range role.Parents {
+			links = append(links, []string{role.Name, parent.Name, rbac.GlobalDomain})
+		}
+	}
+	return rbac.AddGroupingPolicies(links)
+}
+
+// ResolvedPermission 角色有效权限中的一项，Role 为提供该权限的角色（自身或祖先角色）
+type ResolvedPermission struct {
+	model.Permission
+	Role string `json:"role"`
+}
+
+// ResolvedPolicy 角色有效的 Casbin 策略，Role 为策略的主体（自身或祖先角色）
+type ResolvedPolicy struct {
+	rbac.Policy
+	Role string `json:"role"`
+}
+
+// ResolvedRole 角色经继承展开后的完整权限集合
+type ResolvedRole struct {
+	Role        *model.Role          `json:"role"`
+	Inherited   []rbac.RoleSource    `json:"inherited"`   // 继承的全部祖先角色及继承链
+	Permissions []ResolvedPermission `json:"permissions"` // 来自 role_permissions 的权限
+	Policies    []ResolvedPolicy     `json:"policies"`    // 来自 Casbin 的策略
+}
+
+// ResolveRole 展开角色在全局域中的继承链，汇总自身和全部祖先角色的权限与策略
+func (s *RoleService) ResolveRole(ctx context.Context, id uint) (*ResolvedRole, error) {
+	role, err := s.GetRoleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	inherited, err := rbac.ExplainRoles(role.Name, rbac.GlobalDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ResolvedRole{
+		Role:        role,
+		Inherited:   inherited,
+		Permissions: []ResolvedPermission{},
+		Policies:    []ResolvedPolicy{},
+	}
+	if result.Inherited == nil {
+		result.Inherited = []rbac.RoleSource{}
+	}
+
+	names := []string{role.Name}
+	seen := map[string]bool{role.Name: true}
+	for _, source := range inherited {
+		if !seen[source.Role] {
+			seen[source.Role] = true
+			names = append(names, source.Role)
+		}
+	}
+
+	var roles []model.Role
+	if err := database.DB.WithContext(ctx).Preload("Permissions").Where("name IN ?", names).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	byName := make(map[string]model.Role, len(roles))
+	for _, r := range roles {
+		byName[r.Name] = r
+	}
+
+	granted := make(map[uint]bool)
+	for _, name := range names {
+		for _, permission := range byName[name].Permissions {
+			if !granted[permission.ID] {
+				granted[permission.ID] = true
+				result.Permissions = append(result.Permissions, ResolvedPermission{Permission: permission, Role: name})
+			}
+		}
+
+		policies, err := rbac.ListPolicies(rbac.Policy{Subject: name})
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range policies {
+			result.Policies = append(result.Policies, ResolvedPolicy{Policy: policy, Role: name})
+		}
+	}
+	return result, nil
 }
 
 // AssignPermissionToRole 为角色分配权限
-func (s *RoleService) AssignPermissionToRole(roleID, permissionID uint) error {
+func (s *RoleService) AssignPermissionToRole(ctx context.Context, roleID, permissionID uint) error {
 	var role model.Role
 	var permission model.Permission
 
-	if err := database.DB.First(&role, roleID).Error; err != nil {
+	if err := database.DB.WithContext(ctx).First(&role, roleID).Error; err != nil {
 		return err
 	}
 
-	if err := database.DB.First(&permission, permissionID).Error; err != nil {
+	if err := database.DB.WithContext(ctx).First(&permission, permissionID).Error; err != nil {
 		return err
 	}
 
-	return database.DB.Model(&role).Association("Permissions").Append(&permission)
+	return database.DB.WithContext(ctx).Model(&role).Association("Permissions").Append(&permission)
 }
 
 // RemovePermissionFromRole 移除角色权限
-func (s *RoleService) RemovePermissionFromRole(roleID, permissionID uint) error {
+func (s *RoleService) RemovePermissionFromRole(ctx context.Context, roleID, permissionID uint) error {
 	var role model.Role
 	var permission model.Permission
 
-	if err := database.DB.First(&role, roleID).Error; err != nil {
+	if err := database.DB.WithContext(ctx).First(&role, roleID).Error; err != nil {
 		return err
 	}
 
-	if err := database.DB.First(&permission, permissionID).Error; err != nil {
+	if err := database.DB.WithContext(ctx).First(&permission, permissionID).Error; err != nil {
 		return err
 	}
 
-	return database.DB.Model(&role).Association("Permissions").Delete(&permission)
+	return database.DB.WithContext(ctx).Model(&role).Association("Permissions").Delete(&permission)
 }
 
 // GetRolePermissions 获取角色的所有权限
-func (s *RoleService) GetRolePermissions(roleID uint) ([]model.Permission, error) {
+func (s *RoleService) GetRolePermissions(ctx context.Context, roleID uint) ([]model.Permission, error) {
 	var role model.Role
-	if err := database.DB.Preload("Permissions").First(&role, roleID).Error; err != nil {
+	if err := database.DB.WithContext(ctx).Preload("Permissions").First(&role, roleID).Error; err != nil {
 		return nil, err
 	}
 	return role.Permissions, nil
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+)
+
+// SessionService 会话服务
+// JWT 本身无状态，吊销通过在 Redis 中记录“吊销时间点”（毫秒）实现：签发时间不晚于该时间点的 Token 一律失效；
+// 退出登录只吊销当前 Token，按 Token ID（jti）记录
+type SessionService struct{}
+
+// revokedKey 返回用户吊销时间点的 Redis 键
+func revokedKey(userID uint) string {
+	return fmt.Sprintf("session:revoked:%d", userID)
+}
+
+// revokedTokenKey 返回单个 Token 吊销记录的 Redis 键
+func revokedTokenKey(tokenID string) string {
+	return "session:revoked_token:" + tokenID
+}
+
+// RevokeUserSessions 吊销用户当前所有 Token
+// ttl 应不小于 Token 有效期，过期后旧 Token 自然失效，记录也无需保留
+func (s *SessionService) RevokeUserSessions(ctx context.Context, userID uint, ttl time.Duration) error {
+	if database.RDB == nil {
+		return errors.New("redis 未初始化")
+	}
+	return database.RDB.Set(ctx, revokedKey(userID), time.Now().UnixMilli(), ttl).Err()
+}
+
+// RevokeToken 吊销单个 Token，ttl 应不小于 Token 的剩余有效期
+func (s *SessionService) RevokeToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	if database.RDB == nil {
+		return errors.New("redis 未初始化")
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	return database.RDB.Set(ctx, revokedTokenKey(tokenID), 1, ttl).Err()
+}
+
+// IsRevoked 检查用户在 issuedAt 签发、ID 为 tokenID 的 Token 是否已被吊销，tokenID 可为空
+func (s *SessionService) IsRevoked(ctx context.Context, userID uint, tokenID string, issuedAt time.Time) (bool, error) {
+	if database.RDB == nil {
+		return false, nil
+	}
+
+	// 用户吊销时间点和单个 Token 的吊销记录一次取回
+	keys := []string{revokedKey(userID)}
+	if tokenID != "" {
+		keys = append(keys, revokedTokenKey(tokenID))
+	}
+	values, err := database.RDB.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+	if len(values) > 1 && values[1] != nil {
+		return true, nil
+	}
+	value, ok := values[0].(string)
+	if !ok {
+		return false, nil
+	}
+
+	revokedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return issuedAt.UnixMilli() <= revokedAt, nil
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTenantDisabled 租户已被禁用
+	ErrTenantDisabled = errors.New("租户已被禁用")
+	// ErrNotTenantMember 用户不是该租户成员
+	ErrNotTenantMember = errors.New("用户不是该租户成员")
+	// ErrRoleNotGrantable 角色不存在、已禁用，或自身及继承的角色中有不能在租户内授予的角色
+	ErrRoleNotGrantable = errors.New("该角色不能在租户内授予")
+	// ErrRoleNotHeld 授予者本人在该租户内没有这个角色
+	ErrRoleNotHeld = errors.New("不能授予自己没有的角色")
+	// ErrInvalidTenantCode 租户编码不合法
+	ErrInvalidTenantCode = errors.New("租户编码只能包含小写字母、数字、下划线和连字符，以字母或数字开头，长度 2-50")
+)
+
+// tenantCodePattern 租户编码同时是 Casbin 域，g 和 p 的域都按 keyMatch 匹配，
+// 含 * 等字符的编码会成为通配模式，使其中的角色分配在其他租户中生效
+var tenantCodePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,49}$`)
+
+// TenantService 租户服务
+type TenantService struct{}
+
+// CreateTenant 创建租户
+func (s *TenantService) CreateTenant(tenant *model.Tenant) error {
+	if !tenantCodePattern.MatchString(tenant.Code) {
+		return ErrInvalidTenantCode
+	}
+	return database.DB.Create(tenant).Error
+}
+
+// GetTenantByID 根据ID获取租户
+func (s *TenantService) GetTenantByID(id uint) (*model.Tenant, error) {
+	var tenant model.Tenant
+	if err := database.DB.First(&tenant, id).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// GetTenantByCode 根据编码获取租户
+func (s *TenantService) GetTenantByCode(code string) (*model.Tenant, error) {
+	var tenant model.Tenant
+	if err := database.DB.Where("code = ?", code).First(&tenant).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// GetAllTenants 获取所有租户（分页）
+func (s *TenantService) GetAllTenants(page, pageSize int) ([]model.Tenant, int64, error) {
+	var tenants []model.Tenant
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	if err := database.DB.Model(&model.Tenant{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := database.DB.Offset(offset).Limit(pageSize).Find(&tenants).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return tenants, total, nil
+}
+
+// UpdateTenant 更新租户名称、描述和状态（编码即 Casbin 域，创建后不可修改）
+func (s *TenantService) UpdateTenant(tenant *model.Tenant) error {
+	if tenant.Code != "" && !tenantCodePattern.MatchString(tenant.Code) {
+		return ErrInvalidTenantCode
+	}
+	return database.DB.Model(tenant).Select("name", "description", "status").Updates(tenant).Error
+}
+
+// DeleteTenant 删除租户（软删除），同时移除成员关系、租户用户组及该域下的全部角色分配和策略
+func (s *TenantService) DeleteTenant(id uint) error {
+	tenant, err := s.GetTenantByID(id)
+	if err != nil {
+		return err
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ?", id).Delete(&model.TenantMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM group_members WHERE group_id IN (SELECT id FROM groups WHERE tenant_id = ?)", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tenant_id = ?", id).Delete(&model.Group{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tenant).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = rbac.DeleteDomain(tenant.Code)
+	return err
+}
+
+// ResolveTenant 校验用户能否进入指定租户
+// 拥有全局 admin 角色的用户可进入任意租户，其余用户必须是租户成员
+func (s *TenantService) ResolveTenant(code string, userID uint, globalRoles []string) (*model.Tenant, error) {
+	tenant, err := s.GetTenantByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if tenant.Status != 1 {
+		return nil, ErrTenantDisabled
+	}
+
+	for _, role := range globalRoles {
+		if role == "admin" {
+			return tenant, nil
+		}
+	}
+
+	var count int64
+	if err := database.DB.Model(&model.TenantMember{}).
+		Where("tenant_id = ? AND user_id = ?", tenant.ID, userID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrNotTenantMember
+	}
+	return tenant, nil
+}
+
+// GetMembers 获取当前租户的成员（分页）
+// 租户由 ctx 决定（database.WithTenant），查询会被自动限定在该租户内
+func (s *TenantService) GetMembers(ctx context.Context, page, pageSize int) ([]model.TenantMember, int64, error) {
+	var members []model.TenantMember
+	var total int64
+
+	offset := (page - 1) * pageSize
+	db := database.DB.WithContext(ctx)
+
+	if err := db.Model(&model.TenantMember{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := db.Preload("Tenant").Preload("User").Offset(offset).Limit(pageSize).Find(&members).Error; err != nil {
+		return nil, 0, err
+	}
+
+	for i := range members {
+		if members[i].User != nil && members[i].Tenant != nil {
+			members[i].Roles = rbac.GetDomainRolesForUser(members[i].User.Username, members[i].Tenant.Code)
+		}
+	}
+	return members, total, nil
+}
+
+// AddMember 将用户加入当前租户并授予租户内角色；用户已是成员时仅追加角色
+// grantor 为执行操作的用户，授予的角色须通过 checkGrantableRoles 的校验
+func (s *TenantService) AddMember(ctx context.Context, grantor string, userID uint, roles []string) (*model.TenantMember, error) {
+	tenantID, ok := database.TenantFromContext(ctx)
+	if !ok {
+		return nil, errors.New("未指定租户")
+	}
+	tenant, err := s.GetTenantByID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantableRoles(ctx, grantor, tenant.Code, roles); err != nil {
+		return nil, err
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	member := model.TenantMember{UserID: userID}
+	if err := database.DB.WithContext(ctx).Where(&member).FirstOrCreate(&member).Error; err != nil {
+		return nil, err
+	}
+
+	links := make([][]string, 0, len(roles))
+	for _, role := range roles {
+		links = append(links, []string{user.Username, role, tenant.Code})
+	}
+	if _, err := rbac.AddGroupingPolicies(links); err != nil {
+		return nil, err
+	}
+
+	member.Roles = rbac.GetDomainRolesForUser(user.Username, tenant.Code)
+	return &member, nil
+}
+
+// checkGrantableRoles 校验 grantor 能否在租户域 domain 中授予 roles
+// 默认策略都属于全局域，在租户内授予 admin 这样的角色即等同于授予全局权限，因此要求：
+// 角色及其继承的全部角色都在 roles 表中、已启用且范围为 tenant；
+// 除全局管理员外，grantor 本人在该租户内必须拥有所授予的角色
+func checkGrantableRoles(ctx context.Context, grantor, domain string, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	for _, role := range roles {
+		names[role] = true
+		inherited, err := rbac.GetImplicitRolesForUser(role, domain)
+		if err != nil {
+			return err
+		}
+		for _, name := range inherited {
+			names[name] = true
+		}
+	}
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+
+	var records []model.Role
+	if err := database.DB.WithContext(ctx).Where("name IN ?", list).Find(&records).Error; err != nil {
+		return err
+	}
+	grantable := make(map[string]bool, len(records))
+	for _, record := range records {
+		grantable[record.Name] = record.Status == 1 && record.Scope == model.RoleScopeTenant
+	}
+	for _, name := range list {
+		if !grantable[name] {
+			return fmt.Errorf("%w: %s", ErrRoleNotGrantable, name)
+		}
+	}
+
+	globalRoles, err := rbac.GetImplicitRolesForUser(grantor, rbac.GlobalDomain)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(globalRoles, "admin") {
+		return nil
+	}
+	held, err := rbac.GetImplicitRolesForUser(grantor, domain)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if !slices.Contains(held, role) {
+			return fmt.Errorf("%w: %s", ErrRoleNotHeld, role)
+		}
+	}
+	return nil
+}
+
+// RemoveMember 将用户移出当前租户并撤销其租户内角色及用户组关系
+func (s *TenantService) RemoveMember(ctx context.Context, userID uint) error {
+	tenantID, ok := database.TenantFromContext(ctx)
+	if !ok {
+		return errors.New("未指定租户")
+	}
+	tenant, err := s.GetTenantByID(tenantID)
+	if err != nil {
+		return err
+	}
+
+	var user model.User
+	if err := database.DB.Unscoped().First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", userID).Delete(&model.TenantMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// 同时移出该租户下的所有用户组
+		return tx.Exec(
+			"DELETE FROM group_members WHERE user_id = ? AND group_id IN (SELECT id FROM groups WHERE tenant_id = ?)",
+			userID, tenantID,
+		).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = rbac.DeleteRolesForUserInDomain(user.Username, tenant.Code)
+	return err
+}
+
+// GetUserTenants 获取用户所属的租户及其在各租户中的角色
+func (s *TenantService) GetUserTenants(userID uint, username string) ([]model.TenantMember, error) {
+	var members []model.TenantMember
+	if err := database.DB.Preload("Tenant").Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range members {
+		if members[i].Tenant != nil {
+			members[i].Roles = rbac.GetDomainRolesForUser(username, members[i].Tenant.Code)
+		}
+	}
+	return members, nil
+}
@@ -0,0 +1,496 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/logging"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 导入导出支持的格式
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// csvHeader CSV 文件列头，roles 列中多个角色以 ; 分隔
+var csvHeader = []string{"username", "email", "password", "nickname", "status", "roles"}
+
+// errDryRun 用于在试运行结束时回滚事务
+var errDryRun = errors.New("dry run")
+
+// ImportUser 待导入的用户记录
+type ImportUser struct {
+	Username string   `json:"username"`
+	Email    string   `json:"email"`
+	Password string   `json:"password"` // 为空时生成随机密码（导出文件不含密码），在导入结果中返回
+	Nickname string   `json:"nickname"`
+	Status   *int     `json:"status"`
+	Roles    []string `json:"roles"`
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	DryRun  bool // 仅校验，不写入数据库
+	Partial bool // 部分提交：跳过出错的行，其余行照常写入
+}
+
+// ImportRowError 单行导入错误
+type ImportRowError struct {
+	Row      int    `json:"row"` // 从 1 开始，不含 CSV 列头
+	Username string `json:"username"`
+	Error    string `json:"error"`
+}
+
+// ImportResult 导入结果
+type ImportResult struct {
+	Total     int              `json:"total"`
+	Created   int              `json:"created"`
+	Failed    int              `json:"failed"`
+	DryRun    bool             `json:"dry_run"`
+	Committed bool             `json:"committed"`
+	Errors    []ImportRowError `json:"errors"`
+
+	// Passwords 为未提供密码的用户生成的初始密码，仅在提交后返回
+	Passwords []GeneratedPassword `json:"passwords,omitempty"`
+	// Warnings 用户已创建但未完全成功的后续步骤，如 Casbin 角色分配失败
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// GeneratedPassword 导入时生成的初始密码
+type GeneratedPassword struct {
+	Row      int    `json:"row"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// DetectFormat 根据文件名或 Content-Type 推断导入格式
+func DetectFormat(nameOrType string) string {
+	value := strings.ToLower(nameOrType)
+	if strings.HasSuffix(value, ".json") || strings.Contains(value, "json") {
+		return FormatJSON
+	}
+	return FormatCSV
+}
+
+// ParseImportUsers 解析 CSV 或 JSON 格式的用户列表
+func ParseImportUsers(r io.Reader, format string) ([]ImportUser, error) {
+	switch format {
+	case FormatJSON:
+		var users []ImportUser
+		if err := json.NewDecoder(r).Decode(&users); err != nil {
+			return nil, fmt.Errorf("解析 JSON 失败: %w", err)
+		}
+		return users, nil
+	case FormatCSV:
+		return parseImportCSV(r)
+	default:
+		return nil, fmt.Errorf("不支持的格式: %s", format)
+	}
+}
+
+// parseImportCSV 解析 CSV，列顺序以首行列头为准
+func parseImportCSV(r io.Reader) ([]ImportUser, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取 CSV 列头失败: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"username", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV 缺少必需列: %s", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var users []ImportUser
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取 CSV 第 %d 行失败: %w", line, err)
+		}
+
+		user := ImportUser{
+			Username: field(record, "username"),
+			Email:    field(record, "email"),
+			Password: field(record, "password"),
+			Nickname: field(record, "nickname"),
+		}
+		if status := field(record, "status"); status != "" {
+			value, err := strconv.Atoi(status)
+			if err != nil {
+				return nil, fmt.Errorf("CSV 第 %d 行 status 无效: %s", line, status)
+			}
+			user.Status = &value
+		}
+		for _, role := range strings.Split(field(record, "roles"), ";") {
+			if role = strings.TrimSpace(role); role != "" {
+				user.Roles = append(user.Roles, role)
+			}
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// ImportUsers 批量导入用户
+// 所有行在同一事务中写入：默认任一行出错即整体回滚；Partial 模式下仅回滚出错的行
+func (s *UserService) ImportUsers(ctx context.Context, users []ImportUser, opts ImportOptions) (*ImportResult, error) {
+	result := &ImportResult{Total: len(users), DryRun: opts.DryRun, Errors: []ImportRowError{}}
+	if len(users) == 0 {
+		return result, nil
+	}
+
+	roleRecords, err := s.loadImportRoles(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+
+	// 导出文件不含密码，为空的密码生成随机初始密码，使导出的文件可以重新导入
+	users = slices.Clone(users)
+	generated := make(map[string]int)
+	for i := range users {
+		if users[i].Password == "" {
+			users[i].Password = rand.Text()
+			generated[users[i].Username] = i + 1
+		}
+	}
+
+	fail := func(row int, user ImportUser, err error) {
+		result.Errors = append(result.Errors, ImportRowError{Row: row, Username: user.Username, Error: err.Error()})
+	}
+
+	// 先做不依赖数据库写入的校验，同时检查文件内的重复项
+	valid := make([]bool, len(users))
+	seen := make(map[string]int)
+	for i, user := range users {
+		row := i + 1
+		if err := validateImportUser(user, roleRecords); err != nil {
+			fail(row, user, err)
+			continue
+		}
+		if prev, ok := seen["u:"+user.Username]; ok {
+			fail(row, user, fmt.Errorf("用户名与第 %d 行重复", prev))
+			continue
+		}
+		if prev, ok := seen["e:"+strings.ToLower(user.Email)]; ok {
+			fail(row, user, fmt.Errorf("邮箱与第 %d 行重复", prev))
+			continue
+		}
+		seen["u:"+user.Username] = row
+		seen["e:"+strings.ToLower(user.Email)] = row
+		valid[i] = true
+	}
+
+	var created []ImportUser
+	err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, user := range users {
+			if !valid[i] {
+				continue
+			}
+			// 每行使用嵌套事务（保存点），单行失败不会中断整个事务，从而能报告所有出错的行
+			rowErr := tx.Transaction(func(tx *gorm.DB) error {
+				return createImportedUser(tx, user, roleRecords)
+			})
+			if rowErr != nil {
+				fail(i+1, user, rowErr)
+				continue
+			}
+			created = append(created, user)
+		}
+
+		if len(result.Errors) > 0 && !opts.Partial {
+			return errors.New("存在错误行，已全部回滚")
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	result.Failed = len(result.Errors)
+	switch {
+	case err == nil:
+		result.Committed = true
+		result.Created = len(created)
+	case errors.Is(err, errDryRun):
+		result.Created = len(created)
+	case len(result.Errors) == 0:
+		return nil, err
+	}
+
+	if result.Committed {
+		var links [][]string
+		for _, user := range created {
+			if row, ok := generated[user.Username]; ok {
+				result.Passwords = append(result.Passwords, GeneratedPassword{Row: row, Username: user.Username, Password: user.Password})
+			}
+			for _, role := range user.Roles {
+				links = append(links, []string{user.Username, role, rbac.GlobalDomain})
+			}
+		}
+		// 用户已经提交，角色分配失败时作为警告返回，由管理员重新分配，不把整个导入报告为失败
+		if _, err := rbac.AddGroupingPolicies(links); err != nil {
+			logging.FromContext(ctx).Error("导入用户后分配角色失败", "users", len(created), "error", err)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("用户已创建，但分配角色失败，请重新为这些用户分配角色: %v", err))
+		}
+	}
+	return result, nil
+}
+
+// loadImportRoles 查询导入数据中引用到的角色
+// 返回值以角色名为键；值为 nil 表示角色仅存在于 Casbin 策略中而没有对应的 roles 记录
+func (s *UserService) loadImportRoles(ctx context.Context, users []ImportUser) (map[string]*model.Role, error) {
+	names := make(map[string]struct{})
+	for _, user := range users {
+		for _, role := range user.Roles {
+			names[role] = struct{}{}
+		}
+	}
+	if len(names) == 0 {
+		return map[string]*model.Role{}, nil
+	}
+
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+
+	var roles []model.Role
+	if err := database.DB.WithContext(ctx).Where("name IN ?", list).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]*model.Role, len(list))
+	for i := range roles {
+		records[roles[i].Name] = &roles[i]
+	}
+	for _, name := range list {
+		if _, ok := records[name]; ok {
+			continue
+		}
+		exists, err := rbac.RoleExists(name)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			records[name] = nil
+		}
+	}
+	return records, nil
+}
+
+// validateImportUser 校验单行数据
+func validateImportUser(user ImportUser, roles map[string]*model.Role) error {
+	if n := len(user.Username); n < 3 || n > 50 {
+		return errors.New("用户名长度需在 3-50 之间")
+	}
+	if addr, err := mail.ParseAddress(user.Email); err != nil || addr.Address != user.Email {
+		return errors.New("邮箱格式错误")
+	}
+	if len(user.Password) < 6 {
+		return errors.New("密码长度至少为 6")
+	}
+	if user.Status != nil && *user.Status != model.UserStatusActive && *user.Status != model.UserStatusDisabled {
+		return fmt.Errorf("无效的状态: %d", *user.Status)
+	}
+	for _, role := range user.Roles {
+		if _, ok := roles[role]; !ok {
+			return fmt.Errorf("角色不存在: %s", role)
+		}
+	}
+	return nil
+}
+
+// createImportedUser 在事务中创建单个用户并写入用户-角色关联
+func createImportedUser(tx *gorm.DB, user ImportUser, roles map[string]*model.Role) error {
+	var count int64
+	if err := tx.Model(&model.User{}).
+		Where("username = ? OR email = ?", user.Username, user.Email).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUserConflict
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	record := &model.User{
+		Username: user.Username,
+		Email:    user.Email,
+		Password: string(hashedPassword),
+		Nickname: user.Nickname,
+		Status:   model.UserStatusActive,
+	}
+	if user.Status != nil {
+		record.Status = *user.Status
+	}
+	for _, name := range user.Roles {
+		if role := roles[name]; role != nil {
+			record.Roles = append(record.Roles, *role)
+		}
+	}
+
+	// Status 为 0 时需显式写入，否则会被 default:1 覆盖
+	if err := tx.Create(record).Error; err != nil {
+		return err
+	}
+	if record.Status != model.UserStatusActive {
+		return tx.Model(record).Update("status", record.Status).Error
+	}
+	return nil
+}
+
+// ExportUsers 以流式方式导出所有用户（不含密码）
+func (s *UserService) ExportUsers(ctx context.Context, w io.Writer, format string) error {
+	switch format {
+	case FormatCSV:
+		return exportUsersCSV(ctx, w)
+	case FormatJSON:
+		return exportUsersJSON(ctx, w)
+	default:
+		return fmt.Errorf("不支持的格式: %s", format)
+	}
+}
+
+// exportBatchSize 导出时每批读取的用户数
+const exportBatchSize = 200
+
+// eachUserBatch 分批遍历用户，避免一次性加载全部数据
+func eachUserBatch(ctx context.Context, fn func(users []ImportUser) error) error {
+	var users []model.User
+	return database.DB.WithContext(ctx).Preload("Roles").Order("id").FindInBatches(&users, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		if err := dropInactiveRoles(database.DB.WithContext(ctx), userPointers(users)...); err != nil {
+			return err
+		}
+		records := make([]ImportUser, 0, len(users))
+		for _, user := range users {
+			status := user.Status
+			records = append(records, ImportUser{
+				Username: user.Username,
+				Email:    user.Email,
+				Nickname: user.Nickname,
+				Status:   &status,
+				Roles:    exportRoles(user),
+			})
+		}
+		return fn(records)
+	}).Error
+}
+
+// exportRoles 合并 user_roles 关联与 Casbin 中的角色
+func exportRoles(user model.User) []string {
+	seen := make(map[string]struct{})
+	roles := []string{}
+	add := func(name string) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			roles = append(roles, name)
+		}
+	}
+	for _, role := range user.Roles {
+		add(role.Name)
+	}
+	if casbinRoles, err := rbac.GetRolesForUser(user.Username); err == nil {
+		for _, role := range casbinRoles {
+			add(role)
+		}
+	}
+	return roles
+}
+
+func exportUsersCSV(ctx context.Context, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	err := eachUserBatch(ctx, func(users []ImportUser) error {
+		for _, user := range users {
+			record := []string{user.Username, user.Email, "", user.Nickname, strconv.Itoa(*user.Status), strings.Join(user.Roles, ";")}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func exportUsersJSON(ctx context.Context, w io.Writer) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	first := true
+	err := eachUserBatch(ctx, func(users []ImportUser) error {
+		for _, user := range users {
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+
+			data, err := json.Marshal(struct {
+				Username string   `json:"username"`
+				Email    string   `json:"email"`
+				Nickname string   `json:"nickname"`
+				Status   int      `json:"status"`
+				Roles    []string `json:"roles"`
+			}{user.Username, user.Email, user.Nickname, *user.Status, user.Roles})
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
@@ -1,19 +1,34 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/database"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/logging"
 	"github.com/lwmacct/250730-vuetifyjs-template/app/server/model"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/rbac"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrUserConflict 用户名或邮箱已被正常用户占用
+	ErrUserConflict = errors.New("用户名或邮箱已被占用")
+	// ErrUserPurged 用户已被清除，无法恢复
+	ErrUserPurged = errors.New("用户已被清除")
+)
+
 // UserService 用户服务
-type UserService struct{}
+type UserService struct {
+	sessionService SessionService
+}
 
 // CreateUser 创建用户
-func (s *UserService) CreateUser(user *model.User) error {
+func (s *UserService) CreateUser(ctx context.Context, user *model.User) error {
 	// 密码加密
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -21,67 +36,70 @@ func (s *UserService) CreateUser(user *model.User) error {
 	}
 	user.Password = string(hashedPassword)
 
-	return database.DB.Create(user).Error
+	return database.DB.WithContext(ctx).Create(user).Error
 }
 
 // GetUserByID 根据ID获取用户
-func (s *UserService) GetUserByID(id uint) (*model.User, error) {
+func (s *UserService) GetUserByID(ctx context.Context, id uint) (*model.User, error) {
 	var user model.User
-	err := database.DB.Preload("Roles").First(&user, id).Error
+	err := database.DB.WithContext(ctx).Preload("Roles").First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	return &user, dropInactiveRoles(database.DB.WithContext(ctx), &user)
 }
 
 // GetUserByUsername 根据用户名获取用户
-func (s *UserService) GetUserByUsername(username string) (*model.User, error) {
+func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user model.User
-	err := database.DB.Preload("Roles").Where("username = ?", username).First(&user).Error
+	err := database.DB.WithContext(ctx).Preload("Roles").Where("username = ?", username).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	return &user, dropInactiveRoles(database.DB.WithContext(ctx), &user)
 }
 
 // GetUserByEmail 根据邮箱获取用户
-func (s *UserService) GetUserByEmail(email string) (*model.User, error) {
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	err := database.DB.Preload("Roles").Where("email = ?", email).First(&user).Error
+	err := database.DB.WithContext(ctx).Preload("Roles").Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	return &user, dropInactiveRoles(database.DB.WithContext(ctx), &user)
 }
 
 // GetAllUsers 获取所有用户（分页）
-func (s *UserService) GetAllUsers(page, pageSize int) ([]model.User, int64, error) {
+func (s *UserService) GetAllUsers(ctx context.Context, page, pageSize int) ([]model.User, int64, error) {
 	var users []model.User
 	var total int64
 
 	offset := (page - 1) * pageSize
 
-	err := database.DB.Model(&model.User{}).Count(&total).Error
+	err := database.DB.WithContext(ctx).Model(&model.User{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
-	err = database.DB.Preload("Roles").Offset(offset).Limit(pageSize).Find(&users).Error
+	err = database.DB.WithContext(ctx).Preload("Roles").Offset(offset).Limit(pageSize).Find(&users).Error
 	if err != nil {
 		return nil, 0, err
 	}
+	if err := dropInactiveRoles(database.DB.WithContext(ctx), userPointers(users)...); err != nil {
+		return nil, 0, err
+	}
 
 	return users, total, nil
 }
 
 // UpdateUser 更新用户
-func (s *UserService) UpdateUser(user *model.User) error {
-	return database.DB.Save(user).Error
+func (s *UserService) UpdateUser(ctx context.Context, user *model.User) error {
+	return database.DB.WithContext(ctx).Save(user).Error
 }
 
 // DeleteUser 删除用户（软删除）
-func (s *UserService) DeleteUser(id uint) error {
-	return database.DB.Delete(&model.User{}, id).Error
+func (s *UserService) DeleteUser(ctx context.Context, id uint) error {
+	return database.DB.WithContext(ctx).Delete(&model.User{}, id).Error
 }
 
 // VerifyPassword 验证密码
@@ -90,8 +108,8 @@ func (s *UserService) VerifyPassword(user *model.User, password string) error {
 }
 
 // ChangePassword 修改密码
-func (s *UserService) ChangePassword(id uint, oldPassword, newPassword string) error {
-	user, err := s.GetUserByID(id)
+func (s *UserService) ChangePassword(ctx context.Context, id uint, oldPassword, newPassword string) error {
+	user, err := s.GetUserByID(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -108,54 +126,236 @@ func (s *UserService) ChangePassword(id uint, oldPassword, newPassword string) e
 	}
 
 	user.Password = string(hashedPassword)
-	return database.DB.Save(user).Error
+	return database.DB.WithContext(ctx).Save(user).Error
 }
 
-// AssignRoleToUser 为用户分配角色
-func (s *UserService) AssignRoleToUser(userID, roleID uint) error {
+// AssignRoleToUser 为用户分配角色：写入 user_roles 并添加全局域的 g 规则，notBefore、expiresAt 为可选的有效期
+// 带有效期时 g 规则与限时授权一样只在有效期内生效，到期后由清理任务删除。
+// 已拥有的角色不能改为限时分配，限时分配也不能直接改为永久，需要先移除，使各副本随 g 规则的变更重新加载有效期
+func (s *UserService) AssignRoleToUser(ctx context.Context, userID, roleID uint, notBefore, expiresAt *time.Time) error {
 	var user model.User
 	var role model.Role
 
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	if err := database.DB.WithContext(ctx).First(&user, userID).Error; err != nil {
 		return err
 	}
 
-	if err := database.DB.First(&role, roleID).Error; err != nil {
+	if err := database.DB.WithContext(ctx).First(&role, roleID).Error; err != nil {
+		return err
+	}
+
+	if err := validateGrantWindow(notBefore, expiresAt); err != nil {
+		return err
+	}
+
+	timed := notBefore != nil || expiresAt != nil
+	link := []string{user.Username, role.Name, rbac.GlobalDomain}
+	var existing []model.UserRole
+	if err := database.DB.WithContext(ctx).Where("user_id = ? AND role_id = ?", user.ID, role.ID).Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		if timed || existing[0].NotBefore != nil || existing[0].ExpiresAt != nil {
+			return ErrGrantConflict
+		}
+		_, err := rbac.AddGroupingPolicies([][]string{link})
+		return err
+	}
+	if err := checkUserRoleConflict(ctx, link, timed); err != nil {
+		return err
+	}
+
+	userRole := &model.UserRole{
+		UserID:    user.ID,
+		RoleID:    role.ID,
+		NotBefore: notBefore,
+		ExpiresAt: expiresAt,
+	}
+	if err := database.DB.WithContext(ctx).Create(userRole).Error; err != nil {
+		return err
+	}
+
+	// 先登记有效期，使规则一出现就受有效期约束；写入 Casbin 失败时撤销刚写入的关联
+	if timed {
+		rbac.SetGrantWindow(userRoleGrant(user.Username, role.Name, notBefore, expiresAt))
+	}
+	if _, err := rbac.AddGroupingPolicies([][]string{link}); err != nil {
+		rbac.ClearGrantWindow(user.Username, role.Name, rbac.GlobalDomain)
+		if err := database.DB.WithContext(ctx).Delete(userRole).Error; err != nil {
+			logging.FromContext(ctx).Error("撤销用户角色关联失败，数据库与 Casbin 规则不一致",
+				"user_id", user.ID, "role_id", role.ID, "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// checkUserRoleConflict 检查新的 user_roles 关联能否写入 g 规则
+// 已批准的限时授权会在到期时删除同一条 g 规则；限时分配则不能覆盖其他来源（如策略文件）已有的规则
+func checkUserRoleConflict(ctx context.Context, link []string, timed bool) error {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&model.RoleGrant{}).
+		Where("username = ? AND role = ? AND domain = ? AND status = ?", link[0], link[1], link[2], model.RoleGrantApproved).
+		Count(&count).Error
+	if err != nil {
 		return err
 	}
+	if count > 0 {
+		return ErrGrantConflict
+	}
+	if !timed {
+		return nil
+	}
 
-	return database.DB.Model(&user).Association("Roles").Append(&role)
+	granted, err := rbac.HasLink(link[0], link[1], link[2])
+	if err != nil {
+		return err
+	}
+	if granted {
+		return ErrGrantConflict
+	}
+	return nil
 }
 
-// RemoveRoleFromUser 移除用户角色
-func (s *UserService) RemoveRoleFromUser(userID, roleID uint) error {
+// RemoveRoleFromUser 移除用户角色，同时删除对应的 g 规则
+func (s *UserService) RemoveRoleFromUser(ctx context.Context, userID, roleID uint) error {
 	var user model.User
 	var role model.Role
 
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	if err := database.DB.WithContext(ctx).First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	if err := database.DB.WithContext(ctx).First(&role, roleID).Error; err != nil {
 		return err
 	}
 
-	if err := database.DB.First(&role, roleID).Error; err != nil {
+	if err := database.DB.WithContext(ctx).Model(&user).Association("Roles").Delete(&role); err != nil {
 		return err
 	}
 
-	return database.DB.Model(&user).Association("Roles").Delete(&role)
+	if _, err := rbac.RemoveGroupingPolicies([][]string{{user.Username, role.Name, rbac.GlobalDomain}}); err != nil {
+		return err
+	}
+	rbac.ClearGrantWindow(user.Username, role.Name, rbac.GlobalDomain)
+	return nil
+}
+
+// userRoleGrant 将带有效期的 user_roles 关联转换为全局域中带有效期的 g 规则
+func userRoleGrant(username, role string, notBefore, expiresAt *time.Time) rbac.TimedGrant {
+	grant := rbac.TimedGrant{
+		User:        username,
+		Role:        role,
+		Domain:      rbac.GlobalDomain,
+		GrantWindow: rbac.GrantWindow{NotBefore: notBefore},
+	}
+	if expiresAt != nil {
+		grant.ExpiresAt = *expiresAt
+	}
+	return grant
+}
+
+// timedUserRoles 查询全部带有效期的 user_roles 关联
+func timedUserRoles(db *gorm.DB) ([]rbac.TimedGrant, error) {
+	var rows []struct {
+		Username  string
+		Role      string
+		NotBefore *time.Time
+		ExpiresAt *time.Time
+	}
+	err := db.Table("user_roles").
+		Select("users.username, roles.name AS role, user_roles.not_before, user_roles.expires_at").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.not_before IS NOT NULL OR user_roles.expires_at IS NOT NULL").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]rbac.TimedGrant, 0, len(rows))
+	for _, row := range rows {
+		grants = append(grants, userRoleGrant(row.Username, row.Role, row.NotBefore, row.ExpiresAt))
+	}
+	return grants, nil
+}
+
+// userRoleLink 返回 user_roles 关联对应的 g 规则，用户或角色已被清除时返回 nil
+func userRoleLink(db *gorm.DB, userRole *model.UserRole) ([]string, error) {
+	var user model.User
+	var role model.Role
+	if err := db.Unscoped().First(&user, userRole.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := db.Unscoped().First(&role, userRole.RoleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return []string{user.Username, role.Name, rbac.GlobalDomain}, nil
 }
 
 // GetUserRoles 获取用户的所有角色
-func (s *UserService) GetUserRoles(userID uint) ([]model.Role, error) {
+func (s *UserService) GetUserRoles(ctx context.Context, userID uint) ([]model.Role, error) {
 	var user model.User
-	if err := database.DB.Preload("Roles").First(&user, userID).Error; err != nil {
+	if err := database.DB.WithContext(ctx).Preload("Roles").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if err := dropInactiveRoles(database.DB.WithContext(ctx), &user); err != nil {
 		return nil, err
 	}
 	return user.Roles, nil
 }
 
+// dropInactiveRoles 从预加载的 Roles 中去掉不在 user_roles 有效期内（尚未开始或已到期）的角色
+// Preload 只按关联表加载，不检查有效期；到期的关联要等清理任务删除，尚未开始的关联则一直存在
+func dropInactiveRoles(db *gorm.DB, users ...*model.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+
+	now := time.Now()
+	var inactive []model.UserRole
+	err := db.Where("user_id IN ?", ids).
+		Where("(not_before IS NOT NULL AND not_before > ?) OR (expires_at IS NOT NULL AND expires_at <= ?)", now, now).
+		Find(&inactive).Error
+	if err != nil || len(inactive) == 0 {
+		return err
+	}
+
+	skip := make(map[[2]uint]bool, len(inactive))
+	for _, userRole := range inactive {
+		skip[[2]uint{userRole.UserID, userRole.RoleID}] = true
+	}
+	for _, user := range users {
+		user.Roles = slices.DeleteFunc(user.Roles, func(role model.Role) bool {
+			return skip[[2]uint{user.ID, role.ID}]
+		})
+	}
+	return nil
+}
+
+// userPointers 返回指向切片中各个用户的指针
+func userPointers(users []model.User) []*model.User {
+	pointers := make([]*model.User, len(users))
+	for i := range users {
+		pointers[i] = &users[i]
+	}
+	return pointers
+}
+
 // UserExists 检查用户是否存在
-func (s *UserService) UserExists(username, email string) (bool, error) {
+func (s *UserService) UserExists(ctx context.Context, username, email string) (bool, error) {
 	var count int64
-	err := database.DB.Model(&model.User{}).
+	err := database.DB.WithContext(ctx).Model(&model.User{}).
 		Where("username = ? OR email = ?", username, email).
 		Count(&count).Error
 	
@@ -166,3 +366,137 @@ func (s *UserService) UserExists(username, email string) (bool, error) {
 	return count > 0, nil
 }
 
+
+// DisableUser 禁用用户并吊销其所有会话
+func (s *UserService) DisableUser(ctx context.Context, id uint, sessionTTL time.Duration) error {
+	if err := s.setStatus(ctx, id, model.UserStatusDisabled); err != nil {
+		return err
+	}
+	return s.sessionService.RevokeUserSessions(ctx, id, sessionTTL)
+}
+
+// EnableUser 启用用户
+func (s *UserService) EnableUser(ctx context.Context, id uint) error {
+	return s.setStatus(ctx, id, model.UserStatusActive)
+}
+
+// setStatus 更新用户状态
+func (s *UserService) setStatus(ctx context.Context, id uint, status int) error {
+	result := database.DB.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetDeletedUsers 获取已软删除的用户（分页，不含已清除用户）
+func (s *UserService) GetDeletedUsers(ctx context.Context, page, pageSize int) ([]model.User, int64, error) {
+	var users []model.User
+	var total int64
+
+	offset := (page - 1) * pageSize
+	query := database.DB.WithContext(ctx).Unscoped().Model(&model.User{}).
+		Where("deleted_at IS NOT NULL AND status <> ?", model.UserStatusPurged)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("deleted_at DESC").Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// RestoreUser 恢复已软删除的用户
+// 若用户名或邮箱在删除后已被他人使用，则返回 ErrUserConflict
+func (s *UserService) RestoreUser(ctx context.Context, id uint) (*model.User, error) {
+	var user model.User
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error; err != nil {
+			return err
+		}
+		if user.Status == model.UserStatusPurged {
+			return ErrUserPurged
+		}
+
+		var count int64
+		if err := tx.Model(&model.User{}).
+			Where("username = ? OR email = ?", user.Username, user.Email).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUserConflict
+		}
+
+		user.DeletedAt = gorm.DeletedAt{}
+		return tx.Unscoped().Model(&user).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// PurgeUser 彻底清除用户（GDPR）
+// 匿名化个人信息、解除角色、租户和用户组关联并删除 Casbin 中的角色规则，仅保留一条已删除的占位记录
+func (s *UserService) PurgeUser(ctx context.Context, id uint, sessionTTL time.Duration) error {
+	var user model.User
+	if err := database.DB.WithContext(ctx).Unscoped().First(&user, id).Error; err != nil {
+		return err
+	}
+	if user.Status == model.UserStatusPurged {
+		return ErrUserPurged
+	}
+	username := user.Username
+
+	// 须删除用户在所有租户中的成员关系，不按请求中的当前租户过滤
+	ctx = database.WithTenant(ctx, 0)
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.TenantMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM group_members WHERE user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"username": fmt.Sprintf("purged_%d", user.ID),
+			"email":    fmt.Sprintf("purged_%d@invalid", user.ID),
+			"password": "",
+			"nickname": "",
+			"avatar":   "",
+			"status":   model.UserStatusPurged,
+		}
+		if !user.DeletedAt.Valid {
+			updates["deleted_at"] = time.Now()
+		}
+		return tx.Unscoped().Model(&user).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := rbac.DeleteUser(username); err != nil {
+		return fmt.Errorf("删除 Casbin 角色规则失败: %w", err)
+	}
+
+	return s.sessionService.RevokeUserSessions(ctx, id, sessionTTL)
+}
+
+// GetEffectiveRoles 获取用户在指定域中的有效角色及其来源（直接授予或经由用户组继承）
+func (s *UserService) GetEffectiveRoles(ctx context.Context, id uint, domain string) ([]rbac.RoleSource, error) {
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return rbac.ExplainRoles(user.Username, domain)
+}
@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/lwmacct/250730-vuetifyjs-template/app/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 导出器类型
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// Init 按配置初始化全局 TracerProvider 和 W3C trace-context 传播器，返回用于刷新并关闭导出器的函数
+// 未启用时只设置传播器，使上游的 traceparent 仍能透传给下游
+func Init(ctx context.Context, cfg *config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closer, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(version.AppVersion),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		slog.Warn("链路追踪导出失败", "error", err)
+	}))
+
+	slog.Info("链路追踪已启用", "exporter", cfg.Exporter, "service", cfg.ServiceName, "sample_ratio", cfg.SampleRatio)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+// newExporter 创建导出器，file 导出器同时返回需要在关闭时释放的文件
+func newExporter(ctx context.Context, cfg *config.TracingConfig) (sdktrace.SpanExporter, io.Closer, error) {
+	switch strings.ToLower(cfg.Exporter) {
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, options...)
+		return exporter, nil, err
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, nil, err
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("打开链路追踪文件失败: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			_ = file.Close()
+			return nil, nil, err
+		}
+		return exporter, file, nil
+	default:
+		return nil, nil, fmt.Errorf("未知的链路追踪导出器: %s（可选 otlp、stdout、file）", cfg.Exporter)
+	}
+}
+
+// TraceID 返回 ctx 中 span 的 trace ID，没有时返回空字符串
+func TraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
@@ -0,0 +1,301 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Server 主服务的全部监听器：TCP（HTTP 或 HTTPS + HTTP/2）、Unix 套接字、HTTP/3 和 HTTP→HTTPS 重定向
+type Server struct {
+	cfg      *config.Config
+	http     *http.Server
+	h3       *http3.Server
+	redirect *http.Server
+	reloader *CertReloader
+
+	listeners []net.Listener
+	tlsLn     net.Listener
+	redirLn   net.Listener
+	udp       net.PacketConn
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	errs   chan error
+}
+
+// New 按配置创建服务，启用 TLS 时加载证书
+func New(cfg *config.Config, handler http.Handler) (*Server, error) {
+	if cfg.Server.Port <= 0 && cfg.Server.Socket == "" {
+		return nil, errors.New("未配置监听端口或 Unix 套接字")
+	}
+	tlsEnabled := cfg.TLS.Enabled()
+	if !tlsEnabled && (cfg.TLS.HTTP3 || cfg.TLS.RedirectAddr != "") {
+		return nil, errors.New("HTTP/3 和 HTTPS 重定向需要先配置 TLS 证书")
+	}
+	if tlsEnabled && cfg.Server.Port <= 0 {
+		return nil, errors.New("启用 TLS 时需要配置监听端口")
+	}
+
+	s := &Server{cfg: cfg, errs: make(chan error, 4)}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(cfg.Server.H2C)
+
+	if tlsEnabled {
+		reloader, err := NewCertReloader(&cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		s.reloader = reloader
+	}
+
+	if cfg.TLS.HTTP3 {
+		s.h3 = &http3.Server{
+			Addr:           fmt.Sprintf(":%d", cfg.Server.Port),
+			Handler:        handler,
+			TLSConfig:      http3.ConfigureTLSConfig(s.reloader.TLSConfig()),
+			MaxHeaderBytes: 1 << 20,
+			IdleTimeout:    cfg.Server.ReadTimeout * 4,
+		}
+		handler = s.altSvc(handler)
+	}
+
+	s.http = &http.Server{
+		Handler:        handler,
+		ReadTimeout:    cfg.Server.ReadTimeout,
+		WriteTimeout:   cfg.Server.WriteTimeout,
+		MaxHeaderBytes: 1 << 20, // 1MB
+		Protocols:      protocols,
+	}
+	if tlsEnabled {
+		s.http.TLSConfig = s.reloader.TLSConfig()
+	}
+
+	if cfg.TLS.RedirectAddr != "" {
+		s.redirect = &http.Server{
+			Addr:              cfg.TLS.RedirectAddr,
+			Handler:           redirectHandler(cfg.Server.Port),
+			ReadHeaderTimeout: cfg.Server.ReadTimeout,
+		}
+	}
+	return s, nil
+}
+
+// Start 绑定全部监听地址后在后台开始服务；任一地址绑定失败时关闭已绑定的监听器并返回错误。
+// 请求的 context 派生自 ctx，ctx 结束时也停止证书文件的检查
+func (s *Server) Start(ctx context.Context) error {
+	if err := s.listen(); err != nil {
+		s.closeListeners()
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	base := func(net.Listener) context.Context { return ctx }
+	s.http.BaseContext = base
+	if s.redirect != nil {
+		s.redirect.BaseContext = base
+	}
+
+	for _, ln := range s.listeners {
+		s.serve(ln.Addr().String(), func() error { return s.http.Serve(ln) })
+	}
+	if s.tlsLn != nil {
+		s.serve(s.tlsLn.Addr().String(), func() error { return s.http.ServeTLS(s.tlsLn, "", "") })
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.reloader.Watch(ctx, s.cfg.TLS.ReloadInterval)
+		}()
+	}
+	if s.udp != nil {
+		s.serve("udp "+s.udp.LocalAddr().String(), func() error { return s.h3.Serve(s.udp) })
+	}
+	if s.redirLn != nil {
+		s.serve("redirect "+s.redirLn.Addr().String(), func() error { return s.redirect.Serve(s.redirLn) })
+	}
+
+	slog.Info("服务器启动成功", "mode", s.cfg.Server.Mode, "port", s.cfg.Server.Port, "socket", s.cfg.Server.Socket,
+		"tls", s.tlsLn != nil, "http3", s.udp != nil, "h2c", s.cfg.Server.H2C, "redirect", s.cfg.TLS.RedirectAddr)
+	return nil
+}
+
+// Errors 监听器意外退出时的错误，收到后应关闭服务
+func (s *Server) Errors() <-chan error {
+	return s.errs
+}
+
+// Reload 重新加载 TLS 证书，未启用 TLS 时不做任何事
+func (s *Server) Reload() error {
+	if s.reloader == nil {
+		return nil
+	}
+	return s.reloader.Reload()
+}
+
+// Shutdown 停止接收新连接并等待进行中的请求完成，ctx 结束时强制关闭
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	var errs []error
+	if err := s.http.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if s.h3 != nil {
+		if err := s.h3.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("HTTP/3: %w", err))
+		}
+		if s.udp != nil {
+			_ = s.udp.Close()
+		}
+	}
+	if s.redirect != nil {
+		if err := s.redirect.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("重定向服务: %w", err))
+		}
+	}
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
+
+// listen 绑定全部监听地址
+func (s *Server) listen() error {
+	if s.cfg.Server.Port > 0 {
+		addr := fmt.Sprintf(":%d", s.cfg.Server.Port)
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		if s.reloader != nil {
+			s.tlsLn = ln
+		} else {
+			s.listeners = append(s.listeners, ln)
+		}
+	}
+
+	if s.cfg.Server.Socket != "" {
+		ln, err := listenUnix(s.cfg.Server.Socket, s.cfg.Server.SocketMode)
+		if err != nil {
+			return err
+		}
+		s.listeners = append(s.listeners, ln)
+	}
+
+	if s.h3 != nil {
+		udp, err := net.ListenPacket("udp", s.h3.Addr)
+		if err != nil {
+			return err
+		}
+		s.udp = udp
+	}
+
+	if s.redirect != nil {
+		ln, err := net.Listen("tcp", s.redirect.Addr)
+		if err != nil {
+			return err
+		}
+		s.redirLn = ln
+	}
+	return nil
+}
+
+func (s *Server) closeListeners() {
+	for _, ln := range append(s.listeners, s.tlsLn, s.redirLn) {
+		if ln != nil {
+			_ = ln.Close()
+		}
+	}
+	if s.udp != nil {
+		_ = s.udp.Close()
+	}
+}
+
+// serve 在后台运行一个监听器，非正常关闭的错误写入 errs
+func (s *Server) serve(name string, run func() error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := run()
+		if err == nil || errors.Is(err, http.ErrServerClosed) || errors.Is(err, net.ErrClosed) {
+			return
+		}
+		select {
+		case s.errs <- fmt.Errorf("%s: %w", name, err):
+		default:
+		}
+	}()
+}
+
+// altSvc 在 HTTPS 响应中通告 HTTP/3
+func (s *Server) altSvc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			_ = s.h3.SetQUICHeaders(w.Header())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listenUnix 监听 Unix 域套接字，清理上次异常退出残留的套接字文件
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s 已存在且不是套接字", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("%s 正被其他进程使用", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// redirectHandler 将 HTTP 请求重定向到 HTTPS 端口上的同一地址
+func redirectHandler(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		target := "https://" + host + r.URL.RequestURI()
+
+		// GET、HEAD 以外的方法使用 308，保留请求方法和请求体
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, target, status)
+	})
+}
@@ -0,0 +1,166 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lwmacct/250730-vuetifyjs-template/app/server/config"
+)
+
+// CertReloader 持有当前生效的证书和客户端 CA，文件变化或调用 Reload 时原子替换；
+// 已建立的连接不受影响，新的握手使用新证书
+type CertReloader struct {
+	cfg        *config.TLSConfig
+	minVersion uint16
+	clientAuth tls.ClientAuthType
+
+	current atomic.Pointer[tls.Config]
+
+	mu       sync.Mutex
+	modTimes map[string]time.Time
+}
+
+// NewCertReloader 校验 TLS 配置并加载证书
+func NewCertReloader(cfg *config.TLSConfig) (*CertReloader, error) {
+	r := &CertReloader{cfg: cfg, modTimes: make(map[string]time.Time)}
+
+	switch cfg.MinVersion {
+	case "", "1.2":
+		r.minVersion = tls.VersionTLS12
+	case "1.3":
+		r.minVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("不支持的 TLS 最低版本 %q，可选 1.2、1.3", cfg.MinVersion)
+	}
+
+	r.clientAuth = tls.NoClientCert
+	if cfg.ClientCAFile != "" {
+		switch cfg.ClientAuth {
+		case "request":
+			r.clientAuth = tls.RequestClientCert
+		case "require":
+			r.clientAuth = tls.RequireAnyClientCert
+		case "verify-if-given":
+			r.clientAuth = tls.VerifyClientCertIfGiven
+		case "", "require-and-verify":
+			r.clientAuth = tls.RequireAndVerifyClientCert
+		default:
+			return nil, fmt.Errorf("未知的客户端证书校验方式 %q", cfg.ClientAuth)
+		}
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig 返回监听器使用的配置，每次握手取当前生效的证书
+func (r *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: r.minVersion,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+// Reload 重新读取证书、私钥和客户端 CA，任一文件无效时保留原有配置
+func (r *CertReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("加载证书失败: %w", err)
+	}
+	next := &tls.Config{
+		MinVersion:   r.minVersion,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   r.clientAuth,
+	}
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("读取客户端 CA 失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("客户端 CA 文件中没有有效的证书")
+		}
+		next.ClientCAs = pool
+	}
+
+	r.current.Store(next)
+	r.modTimes = modTimes
+	if leaf := cert.Leaf; leaf != nil {
+		slog.Info("TLS 证书已加载", "subject", leaf.Subject.String(), "not_after", leaf.NotAfter)
+	}
+	return nil
+}
+
+// Watch 定时检查证书文件的修改时间，有变化时重新加载，直到 ctx 结束
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				slog.Error("TLS 证书重新加载失败，继续使用原证书", "error", err)
+			}
+		}
+	}
+}
+
+// changed 证书相关文件的修改时间是否与上次加载时不同
+func (r *CertReloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			// 证书轮换时文件可能短暂不存在，下次再检查
+			continue
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *CertReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
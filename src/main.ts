@@ -14,12 +14,16 @@ import * as directives from 'vuetify/directives'
 
 const app = createApp(App)
 
+// 后端注入的 CSP nonce，Vuetify 注入主题样式时需要携带
+const cspNonce = document.querySelector<HTMLMetaElement>('meta[property="csp-nonce"]')?.nonce || undefined
+
 app.use(
   // Vuetify
   createVuetify({
     components,
     directives,
     theme: {
+      cspNonce,
       defaultTheme: 'system',
       themes: {
         dark: {
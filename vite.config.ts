@@ -26,6 +26,10 @@ export default defineConfig({
       '@': fileURLToPath(new URL('./src', import.meta.url)),
     },
   },
+  html: {
+    // 后端按请求把占位符替换为 CSP nonce
+    cspNonce: '__CSP_NONCE__',
+  },
   server: {
     host: true, // 或者使用 '0.0.0.0' 来监听所有网络接口
   },